@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// githubAlertRe matches the marker line of a GitHub alert, e.g. "> [!NOTE]".
+// Group 1 = alert kind.
+var githubAlertRe = regexp.MustCompile(`(?i)^ {0,3}>\s*\[!(note|tip|important|warning|caution)\]\s*$`)
+
+// admonitionRe matches the opening line of a MkDocs admonition, e.g.
+// `!!! warning "Careful"` or the collapsible `???` / `???+` variants.
+// Group 1 = type, group 2 = optional quoted title.
+var admonitionRe = regexp.MustCompile(`^(?:!!!|\?\?\?\+?)\s+([A-Za-z][\w-]*)(?:\s+"([^"]*)")?\s*$`)
+
+// quoteLineRe matches a blockquote continuation line. Group 1 = content.
+var quoteLineRe = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+
+// alertPlaceholderRe matches an INCIPIT_ALERT_N placeholder. Group 1 = index.
+var alertPlaceholderRe = regexp.MustCompile(`INCIPIT_ALERT_(\d+)`)
+
+type alertBlock struct {
+	kind  string // one of note, tip, important, warning, caution
+	title string
+	body  string
+}
+
+// admonitionKinds maps MkDocs admonition types onto the five GitHub alert kinds.
+var admonitionKinds = map[string]string{
+	"note":      "note",
+	"abstract":  "note",
+	"summary":   "note",
+	"info":      "note",
+	"example":   "note",
+	"quote":     "note",
+	"tip":       "tip",
+	"hint":      "tip",
+	"success":   "tip",
+	"check":     "tip",
+	"question":  "tip",
+	"important": "important",
+	"warning":   "warning",
+	"attention": "warning",
+	"caution":   "caution",
+	"danger":    "caution",
+	"error":     "caution",
+	"failure":   "caution",
+	"bug":       "caution",
+}
+
+// alertIcons holds the single-cell glyph shown before each alert title.
+var alertIcons = map[string]string{
+	"note":      "ℹ",
+	"tip":       "★",
+	"important": "❢",
+	"warning":   "⚠",
+	"caution":   "✖",
+}
+
+// extractAlerts pulls GitHub alerts and MkDocs admonitions out of md, replacing
+// each with a unique placeholder paragraph, and returns the modified prose plus
+// the alerts. Plain blockquotes are left untouched.
+func extractAlerts(md string) (string, []alertBlock) {
+	var alerts []alertBlock
+	lines := strings.Split(md, "\n")
+	var out []string
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if sub := githubAlertRe.FindStringSubmatch(line); sub != nil {
+			kind := strings.ToLower(sub[1])
+			var body []string
+			for i+1 < len(lines) {
+				q := quoteLineRe.FindStringSubmatch(lines[i+1])
+				if q == nil {
+					break
+				}
+				body = append(body, q[1])
+				i++
+			}
+			out = append(out, alertPlaceholder(len(alerts)))
+			alerts = append(alerts, alertBlock{kind: kind, title: alertTitle(kind), body: strings.Join(body, "\n")})
+			continue
+		}
+
+		if sub := admonitionRe.FindStringSubmatch(line); sub != nil {
+			kind, ok := admonitionKinds[strings.ToLower(sub[1])]
+			if !ok {
+				kind = "note"
+			}
+			title := sub[2]
+			if title == "" {
+				title = alertTitle(strings.ToLower(sub[1]))
+			}
+			var body []string
+			for i+1 < len(lines) {
+				next := lines[i+1]
+				if strings.TrimSpace(next) == "" {
+					body = append(body, "")
+					i++
+					continue
+				}
+				if strings.HasPrefix(next, "\t") {
+					body = append(body, next[1:])
+				} else if strings.HasPrefix(next, "    ") {
+					body = append(body, next[4:])
+				} else {
+					break
+				}
+				i++
+			}
+			// Trailing blank lines belong to the surrounding document.
+			for len(body) > 0 && body[len(body)-1] == "" {
+				body = body[:len(body)-1]
+				i--
+			}
+			out = append(out, alertPlaceholder(len(alerts)))
+			alerts = append(alerts, alertBlock{kind: kind, title: title, body: strings.Join(body, "\n")})
+			continue
+		}
+
+		out = append(out, line)
+	}
+
+	if len(alerts) == 0 {
+		return md, nil
+	}
+	return strings.Join(out, "\n"), alerts
+}
+
+// alertPlaceholder returns the placeholder paragraph for alert n. The blank
+// lines keep it from merging with adjacent paragraphs.
+func alertPlaceholder(n int) string {
+	return fmt.Sprintf("\nINCIPIT_ALERT_%d\n", n)
+}
+
+// alertTitle returns the default title for an alert kind, e.g. "Warning".
+func alertTitle(kind string) string {
+	if kind == "" {
+		return ""
+	}
+	return strings.ToUpper(kind[:1]) + kind[1:]
+}
+
+// alertColor returns the 256-color index used for an alert's border and title
+// in the given style ("dark", "light", or anything else → dark defaults).
+func alertColor(kind, style string) string {
+	if style == "light" {
+		switch kind {
+		case "tip":
+			return "28"
+		case "important":
+			return "91"
+		case "warning":
+			return "130"
+		case "caution":
+			return "124"
+		default:
+			return "26"
+		}
+	}
+	switch kind {
+	case "tip":
+		return "35"
+	case "important":
+		return "135"
+	case "warning":
+		return "178"
+	case "caution":
+		return "167"
+	default:
+		return "33"
+	}
+}
+
+// renderAlert renders a single alert as a rounded callout box with an icon and
+// title in the top border. The body is rendered as markdown at the box's inner
+// width with the document's options. For "notty" style the box carries no ANSI
+// codes.
+func renderAlert(a alertBlock, width int, style string, opts renderOptions) string {
+	innerWidth := width - 4
+	if innerWidth < 1 {
+		innerWidth = 1
+	}
+
+	title := alertIcons[a.kind] + " " + a.title
+	var color string
+	if style != "notty" {
+		color = alertColor(a.kind, style)
+		title = lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Bold(true).Render(title)
+	}
+
+	var lines []string
+	if strings.TrimSpace(a.body) != "" {
+		// glamour indents prose by two columns; drop the margin so the text
+		// lines up with nested code blocks, which span the full inner width.
+		body := renderDocument(a.body, style, innerWidth, nestedOptions(opts))
+		for _, line := range strings.Split(body, "\n") {
+			if strings.HasPrefix(stripANSI(line), "  ") {
+				line = ansi.TruncateLeft(line, 2, "")
+			}
+			lines = append(lines, line)
+		}
+		lines = trimBlankLines(lines)
+	}
+
+	return renderFrame(title, lines, width, color)
+}
+
+// trimBlankLines drops leading and trailing lines that are visually empty.
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(stripANSI(lines[0])) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(stripANSI(lines[len(lines)-1])) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// injectAlerts replaces INCIPIT_ALERT_N placeholder lines in rendered with the
+// fully-rendered callout box for each corresponding alert.
+func injectAlerts(rendered string, alerts []alertBlock, width int, style string, opts renderOptions) string {
+	lines := strings.Split(rendered, "\n")
+	for i, line := range lines {
+		sub := alertPlaceholderRe.FindStringSubmatch(stripANSI(line))
+		if sub == nil {
+			continue
+		}
+		n, err := strconv.Atoi(sub[1])
+		if err != nil || n >= len(alerts) {
+			continue
+		}
+		lines[i] = renderAlert(alerts[n], width, style, opts)
+	}
+	return strings.Join(lines, "\n")
+}
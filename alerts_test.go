@@ -0,0 +1,150 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// extractAlerts tests
+
+func TestExtractAlerts_GitHubNote(t *testing.T) {
+	md := "Intro\n\n> [!NOTE]\n> Useful info.\n> More info.\n\nAfter."
+	prose, alerts := extractAlerts(md)
+	if len(alerts) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(alerts))
+	}
+	if alerts[0].kind != "note" || alerts[0].title != "Note" {
+		t.Errorf("unexpected kind/title: %q/%q", alerts[0].kind, alerts[0].title)
+	}
+	if alerts[0].body != "Useful info.\nMore info." {
+		t.Errorf("unexpected body: %q", alerts[0].body)
+	}
+	if !strings.Contains(prose, "INCIPIT_ALERT_0") {
+		t.Errorf("expected placeholder in prose, got %q", prose)
+	}
+	if strings.Contains(prose, "[!NOTE]") {
+		t.Error("expected alert marker to be removed from prose")
+	}
+	if !strings.Contains(prose, "After.") {
+		t.Error("expected trailing prose to be preserved")
+	}
+}
+
+func TestExtractAlerts_AllGitHubKinds(t *testing.T) {
+	for _, kind := range []string{"NOTE", "TIP", "IMPORTANT", "WARNING", "CAUTION"} {
+		_, alerts := extractAlerts("> [!" + kind + "]\n> body\n")
+		if len(alerts) != 1 {
+			t.Fatalf("%s: expected 1 alert, got %d", kind, len(alerts))
+		}
+		if alerts[0].kind != strings.ToLower(kind) {
+			t.Errorf("%s: expected kind %q, got %q", kind, strings.ToLower(kind), alerts[0].kind)
+		}
+	}
+}
+
+func TestExtractAlerts_PlainBlockquoteUntouched(t *testing.T) {
+	md := "> just a quote\n> second line\n"
+	prose, alerts := extractAlerts(md)
+	if len(alerts) != 0 {
+		t.Errorf("expected 0 alerts, got %d", len(alerts))
+	}
+	if prose != md {
+		t.Errorf("expected prose unchanged, got %q", prose)
+	}
+}
+
+func TestExtractAlerts_MkDocsWithTitle(t *testing.T) {
+	md := "!!! danger \"Do not do this\"\n    First line.\n\n    Second paragraph.\n\nOutside."
+	prose, alerts := extractAlerts(md)
+	if len(alerts) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(alerts))
+	}
+	if alerts[0].kind != "caution" {
+		t.Errorf("expected danger to map to caution, got %q", alerts[0].kind)
+	}
+	if alerts[0].title != "Do not do this" {
+		t.Errorf("expected custom title, got %q", alerts[0].title)
+	}
+	if alerts[0].body != "First line.\n\nSecond paragraph." {
+		t.Errorf("unexpected body: %q", alerts[0].body)
+	}
+	if !strings.Contains(prose, "Outside.") {
+		t.Error("expected unindented prose after the admonition to be preserved")
+	}
+}
+
+func TestExtractAlerts_MkDocsDefaultTitle(t *testing.T) {
+	_, alerts := extractAlerts("!!! info\n    Body.\n")
+	if len(alerts) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(alerts))
+	}
+	if alerts[0].kind != "note" || alerts[0].title != "Info" {
+		t.Errorf("unexpected kind/title: %q/%q", alerts[0].kind, alerts[0].title)
+	}
+}
+
+// renderAlert tests
+
+func TestRenderAlert_TitleInBorder(t *testing.T) {
+	a := alertBlock{kind: "warning", title: "Warning", body: "Be careful."}
+	out := stripANSI(renderAlert(a, 40, "dark", renderOptions{}))
+	lines := strings.Split(out, "\n")
+	if !strings.HasPrefix(lines[0], "╭── ⚠ Warning ─") {
+		t.Errorf("expected icon and title in top border, got %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[len(lines)-1], "╰") {
+		t.Errorf("expected rounded bottom border, got %q", lines[len(lines)-1])
+	}
+	if !strings.Contains(out, "Be careful.") {
+		t.Errorf("expected body text in output, got %q", out)
+	}
+}
+
+func TestRenderAlert_ConsistentWidth(t *testing.T) {
+	a := alertBlock{kind: "tip", title: "Tip", body: "Some words that wrap across several lines inside the box."}
+	out := stripANSI(renderAlert(a, 30, "dark", renderOptions{}))
+	for _, line := range strings.Split(out, "\n") {
+		if w := len([]rune(line)); w != 30 {
+			t.Errorf("expected every line to be 30 columns, got %d: %q", w, line)
+		}
+	}
+}
+
+func TestRenderAlert_ColorsDifferByKind(t *testing.T) {
+	if alertColor("note", "dark") == alertColor("caution", "dark") {
+		t.Error("expected note and caution to use different colors")
+	}
+	if alertColor("warning", "dark") == alertColor("warning", "light") {
+		t.Error("expected dark and light themes to use different warning colors")
+	}
+}
+
+func TestRenderAlert_NottyNoANSI(t *testing.T) {
+	a := alertBlock{kind: "note", title: "Note", body: "Plain."}
+	out := renderAlert(a, 40, "notty", renderOptions{})
+	if out != stripANSI(out) {
+		t.Error("expected no ANSI codes in notty alert output")
+	}
+	if !strings.Contains(out, "╭") {
+		t.Error("expected border characters even in notty output")
+	}
+}
+
+// End-to-end tests
+
+func TestRenderMarkdown_Alert_EndToEnd(t *testing.T) {
+	md := "Intro.\n\n> [!CAUTION]\n> Dangerous.\n\nOutro."
+	out := stripANSI(renderMarkdown(md, "dark", 60))
+	if strings.Contains(out, "[!CAUTION]") {
+		t.Error("expected literal alert marker to be gone")
+	}
+	if strings.Contains(out, "INCIPIT_ALERT") {
+		t.Error("expected alert placeholder to be replaced")
+	}
+	if !strings.Contains(out, "✖ Caution") {
+		t.Errorf("expected caution title in output, got %q", out)
+	}
+	if !strings.Contains(out, "Dangerous.") || !strings.Contains(out, "Outro.") {
+		t.Errorf("expected alert body and surrounding prose, got %q", out)
+	}
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// pagerSettings holds the pager's command-line settings, shared by every
+// document opened in a session.
+type pagerSettings struct {
+	width    int           // --width, 0 to follow the window
+	maxWidth int           // --max-width, 0 to use the project config
+	opts     renderOptions // noBorders, numberHeadings, showAnchors and graphics
+}
+
+// newPager returns a pager for a file's contents with the project config of
+// the file's directory applied.
+func (s pagerSettings) newPager(filename, content, style string) (model, error) {
+	cfg, err := loadConfig(filepath.Dir(filename))
+	if err != nil {
+		return model{}, err
+	}
+	m := newModel(filename, content, style)
+	m.width = s.width
+	m.maxWidth = cfg.maxWidth
+	if s.maxWidth > 0 {
+		m.maxWidth = s.maxWidth
+	}
+	m.reading = m.maxWidth > 0
+	m.opts.noBorders = s.opts.noBorders
+	m.opts.graphics = s.opts.graphics
+	m.opts.numberHeadings = s.opts.numberHeadings
+	m.opts.showAnchors = s.opts.showAnchors
+	cfg.apply(&m.opts)
+	return m, nil
+}
+
+// fuzzyScore reports whether the characters of query appear in order in s,
+// ignoring case, and scores the match: consecutive characters and characters
+// starting a path segment or word score higher.
+func fuzzyScore(query, s string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	if len(q) == 0 {
+		return 0, true
+	}
+	score, qi, last := 0, 0, -2
+	runes := []rune(strings.ToLower(s))
+	for i, r := range runes {
+		if r != q[qi] {
+			continue
+		}
+		score++
+		if i == last+1 {
+			score += 5
+		}
+		if i == 0 || strings.ContainsRune("/-_. ", runes[i-1]) {
+			score += 3
+		}
+		last = i
+		if qi++; qi == len(q) {
+			return score, true
+		}
+	}
+	return 0, false
+}
+
+// treeRow is a row of the browser's list: a directory or a file.
+type treeRow struct {
+	path  string // relative to the browsed directory, slash-separated
+	name  string // text shown for the row
+	depth int    // nesting level in the tree
+	dir   bool
+}
+
+// treeRows lays out files, sorted paths relative to a directory, as a tree
+// with a row for each directory before its contents. The contents of
+// collapsed directories are left out.
+func treeRows(files []string, collapsed map[string]bool) []treeRow {
+	var rows []treeRow
+	var prev []string // directories of the previous file
+	for _, f := range files {
+		parts := strings.Split(f, "/")
+		dirs := parts[:len(parts)-1]
+		common := 0
+		for common < len(dirs) && common < len(prev) && dirs[common] == prev[common] {
+			common++
+		}
+		prev = dirs
+		hidden := false
+		for d, name := range dirs {
+			path := strings.Join(dirs[:d+1], "/")
+			if d >= common {
+				rows = append(rows, treeRow{path: path, name: name, depth: d, dir: true})
+			}
+			if collapsed[path] {
+				hidden = true
+				break
+			}
+		}
+		if !hidden {
+			rows = append(rows, treeRow{path: f, name: parts[len(parts)-1], depth: len(dirs)})
+		}
+	}
+	return rows
+}
+
+// browser shows the markdown files under a directory as a tree of collapsible
+// directories with a preview of the selected file, and opens files in the
+// pager. While filtering, the matching files are listed by path instead, best
+// match first.
+type browser struct {
+	root     string
+	files    []string // markdown files under root, relative to it, in path order
+	style    string
+	settings pagerSettings
+
+	filter    string
+	filtering bool
+	collapsed map[string]bool // directories folded in the tree
+	rows      []treeRow       // the tree, or the files matching filter
+	matches   int             // files matching filter
+	cursor    int             // index in rows of the selected row
+	offset    int             // index in rows of the first visible row
+
+	previews map[string]string // rendered preview of each file, by width
+	doc      *model            // open document, nil while browsing
+	err      string
+
+	width, height int
+}
+
+// newBrowser returns a browser over files, which are paths under root.
+func newBrowser(root string, files []string, style string, settings pagerSettings) browser {
+	b := browser{root: root, style: style, settings: settings, collapsed: map[string]bool{}, previews: map[string]string{}}
+	for _, f := range files {
+		if rel, err := filepath.Rel(root, f); err == nil {
+			f = rel
+		}
+		b.files = append(b.files, filepath.ToSlash(f))
+	}
+	sort.Strings(b.files)
+	b.applyFilter()
+	return b
+}
+
+// applyFilter shows the tree, or the files matching the filter when there is
+// one, and selects the first row.
+func (b *browser) applyFilter() {
+	b.cursor, b.offset = 0, 0
+	if b.filter == "" {
+		b.rows = treeRows(b.files, b.collapsed)
+		b.matches = len(b.files)
+		return
+	}
+	type match struct {
+		file  string
+		score int
+	}
+	var matches []match
+	for _, f := range b.files {
+		if score, ok := fuzzyScore(b.filter, f); ok {
+			matches = append(matches, match{f, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return len(matches[i].file) < len(matches[j].file)
+	})
+	b.rows = nil
+	for _, m := range matches {
+		b.rows = append(b.rows, treeRow{path: m.file, name: m.file})
+	}
+	b.matches = len(matches)
+}
+
+// selected returns the row under the cursor.
+func (b browser) selected() (treeRow, bool) {
+	if len(b.rows) == 0 {
+		return treeRow{}, false
+	}
+	return b.rows[b.cursor], true
+}
+
+// setCollapsed folds or unfolds directory dir in the tree and selects it.
+func (b *browser) setCollapsed(dir string, collapsed bool) {
+	if b.filter != "" || b.collapsed[dir] == collapsed {
+		return
+	}
+	b.collapsed[dir] = collapsed
+	b.rows = treeRows(b.files, b.collapsed)
+	for i, r := range b.rows {
+		if r.dir && r.path == dir {
+			b.cursor = i
+		}
+	}
+	b.move(0)
+}
+
+// collapseSelected folds the selected directory, or the one holding the
+// selected file.
+func (b *browser) collapseSelected() {
+	r, ok := b.selected()
+	if !ok {
+		return
+	}
+	if !r.dir || b.collapsed[r.path] {
+		r.path = path.Dir(r.path)
+	}
+	if r.path != "." {
+		b.setCollapsed(r.path, true)
+	}
+}
+
+// listHeight returns the number of file rows on screen.
+func (b browser) listHeight() int {
+	return max(1, b.height-headerLines-footerLines)
+}
+
+// paneWidths returns the widths of the file list and the preview.
+func (b browser) paneWidths() (list, preview int) {
+	list = min(40, max(20, b.width/3))
+	return list, max(0, b.width-list-3)
+}
+
+// move selects the file delta rows away, scrolling the list to keep it on
+// screen.
+func (b *browser) move(delta int) {
+	if len(b.rows) == 0 {
+		return
+	}
+	b.cursor = min(max(b.cursor+delta, 0), len(b.rows)-1)
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if h := b.listHeight(); b.cursor >= b.offset+h {
+		b.offset = b.cursor - h + 1
+	}
+}
+
+// open loads the selected file into the pager, or folds or unfolds the
+// selected directory.
+func (b *browser) open() tea.Cmd {
+	r, ok := b.selected()
+	if !ok {
+		return nil
+	}
+	if r.dir {
+		b.setCollapsed(r.path, !b.collapsed[r.path])
+		return nil
+	}
+	path := filepath.Join(b.root, filepath.FromSlash(r.path))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		b.err = err.Error()
+		return nil
+	}
+	m, err := b.settings.newPager(path, string(data), b.style)
+	if err != nil {
+		b.err = err.Error()
+		return nil
+	}
+	m.root = b.root
+	tm, cmd := m.Update(tea.WindowSizeMsg{Width: b.width, Height: b.height})
+	doc := tm.(model)
+	b.doc = &doc
+	b.err = ""
+	return cmd
+}
+
+// preview returns the first lines of the selected file, rendered at width.
+func (b browser) preview(width int) string {
+	r, ok := b.selected()
+	if !ok || r.dir || width < 10 {
+		return ""
+	}
+	file := r.path
+	key := fmt.Sprintf("%s:%d", file, width)
+	if p, ok := b.previews[key]; ok {
+		return p
+	}
+	path := filepath.Join(b.root, filepath.FromSlash(file))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err.Error()
+	}
+	opts := b.settings.opts
+	opts.baseDir = filepath.Dir(path)
+	opts.graphics = "" // inline images cannot be drawn inside a pane
+	b.previews[key] = renderStatic(string(data), b.style, width, opts)
+	return b.previews[key]
+}
+
+func (b browser) Init() tea.Cmd {
+	return nil
+}
+
+func (b browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if size, ok := msg.(tea.WindowSizeMsg); ok {
+		b.width, b.height = size.Width, size.Height
+		b.move(0)
+	}
+	if b.doc != nil {
+		// Esc leaves the document unless the pager is using it to end a
+		// search or task mode.
+		if key, ok := msg.(tea.KeyMsg); ok && key.Type == tea.KeyEsc && !b.doc.searching && !b.doc.taskMode &&
+			!b.doc.docSearch.typing && !b.doc.docSearch.active {
+			b.doc.docSearch.stop()
+			b.doc = nil
+			return b, nil
+		}
+		tm, cmd := b.doc.Update(msg)
+		doc := tm.(model)
+		b.doc = &doc
+		return b, cmd
+	}
+
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return b, nil
+	}
+	if b.filtering {
+		switch key.Type {
+		case tea.KeyEnter:
+			b.filtering = false
+			return b, b.open()
+		case tea.KeyEsc:
+			b.filtering = false
+			b.filter = ""
+			b.applyFilter()
+		case tea.KeyBackspace:
+			if runes := []rune(b.filter); len(runes) > 0 {
+				b.filter = string(runes[:len(runes)-1])
+				b.applyFilter()
+			}
+		case tea.KeyUp:
+			b.move(-1)
+		case tea.KeyDown:
+			b.move(1)
+		case tea.KeyRunes, tea.KeySpace:
+			b.filter += string(key.Runes)
+			b.applyFilter()
+		}
+		return b, nil
+	}
+	switch key.String() {
+	case "q", "ctrl+c":
+		return b, tea.Quit
+	case "up", "k":
+		b.move(-1)
+	case "down", "j":
+		b.move(1)
+	case "pgup", "b":
+		b.move(-b.listHeight())
+	case "pgdown", "f", " ":
+		b.move(b.listHeight())
+	case "g", "home":
+		b.move(-len(b.rows))
+	case "G", "end":
+		b.move(len(b.rows))
+	case "left", "h":
+		b.collapseSelected()
+	case "right", "l":
+		if r, ok := b.selected(); ok && r.dir {
+			b.setCollapsed(r.path, false)
+		}
+	case "/":
+		b.filtering = true
+	case "esc":
+		if b.filter != "" {
+			b.filter = ""
+			b.applyFilter()
+		}
+	case "enter":
+		return b, b.open()
+	}
+	return b, nil
+}
+
+func (b browser) View() string {
+	if b.doc != nil {
+		return b.doc.View()
+	}
+	if b.width == 0 {
+		return "\n  Loading..."
+	}
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("99"))
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	selectedStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("231")).Background(lipgloss.Color("57"))
+	if b.style == "light" {
+		selectedStyle = selectedStyle.Foreground(lipgloss.Color("16")).Background(lipgloss.Color("153"))
+	}
+
+	count := fmt.Sprintf("  %d of %d files", b.matches, len(b.files))
+	header := lipgloss.NewStyle().Width(b.width).Render(" " + headerStyle.Render(b.root) + dimStyle.Render(count))
+
+	listWidth, previewWidth := b.paneWidths()
+	var preview []string
+	if p := b.preview(previewWidth); p != "" {
+		preview = strings.Split(p, "\n")
+	}
+	rows := make([]string, b.listHeight())
+	for i := range rows {
+		n := b.offset + i
+		var row string
+		switch {
+		case n < len(b.rows):
+			r := b.rows[n]
+			label := "  " + r.name
+			if r.dir {
+				label = "▾ " + r.name + "/"
+				if b.collapsed[r.path] {
+					label = "▸ " + r.name + "/"
+				}
+			}
+			row = " " + ansi.Truncate(strings.Repeat("  ", r.depth)+label, listWidth-2, "…")
+			row += strings.Repeat(" ", max(0, listWidth-ansi.StringWidth(row)))
+			if n == b.cursor {
+				row = selectedStyle.Render(row)
+			}
+		case n == 0:
+			row = dimStyle.Render(ansi.Truncate(" no markdown files", listWidth, ""))
+			row += strings.Repeat(" ", max(0, listWidth-ansi.StringWidth(row)))
+		default:
+			row = strings.Repeat(" ", listWidth)
+		}
+		row += dimStyle.Render(" │ ")
+		if i < len(preview) {
+			row += ansi.Truncate(preview[i], previewWidth, "")
+		}
+		rows[i] = row
+	}
+
+	var footerContent string
+	switch {
+	case b.filtering:
+		footerContent = "/" + b.filter + "_"
+	case b.err != "":
+		footerContent = " " + b.err
+	case b.filter != "":
+		footerContent = fmt.Sprintf(" filter: %s  esc clear  enter open  q quit", b.filter)
+	default:
+		footerContent = " ↑/k ↓/j  ←/h →/l fold  / filter  enter open  q quit"
+	}
+	footer := dimStyle.Width(b.width).Render(footerContent)
+	return fmt.Sprintf("%s\n%s\n%s", header, strings.Join(rows, "\n"), footer)
+}
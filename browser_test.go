@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	if _, ok := fuzzyScore("gdr", "docs/guide/readme.md"); !ok {
+		t.Error("expected an in-order subsequence to match")
+	}
+	if _, ok := fuzzyScore("rg", "guide.md"); ok {
+		t.Error("expected out-of-order characters not to match")
+	}
+	exact, _ := fuzzyScore("guide", "docs/guide.md")
+	scattered, _ := fuzzyScore("guide", "go/unit/index/design.md")
+	if exact <= scattered {
+		t.Errorf("expected a consecutive match to score higher, got %d <= %d", exact, scattered)
+	}
+}
+
+func TestTreeRows(t *testing.T) {
+	files := []string{"README.md", "docs/api/auth.md", "docs/api/users.md", "docs/guide.md", "notes.md"}
+	var got []string
+	for _, r := range treeRows(files, map[string]bool{}) {
+		got = append(got, fmt.Sprintf("%d:%s:%v", r.depth, r.name, r.dir))
+	}
+	want := "0:README.md:false 0:docs:true 1:api:true 2:auth.md:false 2:users.md:false 1:guide.md:false 0:notes.md:false"
+	if strings.Join(got, " ") != want {
+		t.Errorf("got %q, want %q", strings.Join(got, " "), want)
+	}
+
+	got = nil
+	for _, r := range treeRows(files, map[string]bool{"docs/api": true}) {
+		got = append(got, r.path)
+	}
+	if want := "README.md docs docs/api docs/guide.md notes.md"; strings.Join(got, " ") != want {
+		t.Errorf("expected the collapsed directory's files left out, got %q", strings.Join(got, " "))
+	}
+}
+
+// rowPaths returns the paths of the browser's rows.
+func rowPaths(b browser) string {
+	var paths []string
+	for _, r := range b.rows {
+		paths = append(paths, r.path)
+	}
+	return strings.Join(paths, ",")
+}
+
+// sendKeys feeds keys to a tea model, one KeyMsg per string: a single rune
+// or a key name such as "enter" or "esc".
+func sendKeys(tm tea.Model, keys ...string) tea.Model {
+	for _, k := range keys {
+		var msg tea.KeyMsg
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		case "left":
+			msg = tea.KeyMsg{Type: tea.KeyLeft}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		tm, _ = tm.Update(msg)
+	}
+	return tm
+}
+
+func TestBrowser_FilterOpenAndBack(t *testing.T) {
+	dir := writeDocs(t, map[string]string{
+		"README.md":         "# Readme\n\nTop level.\n",
+		"docs/guide.md":     "# Guide\n\nHow to use it.\n",
+		"docs/api.md":       "# API\n\nEndpoints.\n",
+		"docs/changelog.md": "# Changes\n",
+	})
+	files, err := markdownFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tm tea.Model = newBrowser(dir, files, "notty", pagerSettings{})
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 20})
+
+	b := tm.(browser)
+	if got := rowPaths(b); got != "README.md,docs,docs/api.md,docs/changelog.md,docs/guide.md" {
+		t.Fatalf("unexpected tree %q", got)
+	}
+	view := stripANSI(b.View())
+	if !strings.Contains(view, "Readme") || !strings.Contains(view, "4 of 4 files") {
+		t.Errorf("expected the tree with a preview of the first file, got:\n%s", view)
+	}
+	if !strings.Contains(view, "▾ docs/") || !strings.Contains(view, "    guide.md") {
+		t.Errorf("expected files indented under their directory, got:\n%s", view)
+	}
+
+	tm = sendKeys(tm, "/", "g", "u", "i")
+	b = tm.(browser)
+	if got := rowPaths(b); got != "docs/guide.md" {
+		t.Fatalf("expected the filter to narrow to the guide, got %q", got)
+	}
+
+	tm = sendKeys(tm, "enter")
+	b = tm.(browser)
+	if b.doc == nil || b.doc.filename != filepath.Join(dir, "docs", "guide.md") {
+		t.Fatalf("expected the guide to open, got %+v", b.doc)
+	}
+	if view := stripANSI(b.View()); !strings.Contains(view, "How to use it.") {
+		t.Errorf("expected the document in the pager, got:\n%s", view)
+	}
+
+	// Esc ends a search in the document before it leaves the document.
+	tm = sendKeys(tm, "/", "esc")
+	if tm.(browser).doc == nil {
+		t.Fatal("expected esc to end the search, not close the document")
+	}
+	tm = sendKeys(tm, "esc")
+	b = tm.(browser)
+	if b.doc != nil || b.filter != "gui" {
+		t.Errorf("expected esc to return to the filtered list, got doc=%v filter=%q", b.doc != nil, b.filter)
+	}
+
+	tm = sendKeys(tm, "esc", "down", "down", "down")
+	if b = tm.(browser); b.filter != "" || b.rows[b.cursor].path != "docs/changelog.md" {
+		t.Errorf("expected esc to clear the filter, got filter=%q selected=%q", b.filter, b.rows[b.cursor].path)
+	}
+}
+
+func TestBrowser_CollapseDirectories(t *testing.T) {
+	dir := writeDocs(t, map[string]string{
+		"README.md":     "# Readme\n",
+		"docs/guide.md": "# Guide\n",
+		"docs/api.md":   "# API\n",
+	})
+	files, err := markdownFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tm tea.Model = newBrowser(dir, files, "notty", pagerSettings{})
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 20})
+
+	// Enter on a directory folds it instead of opening anything.
+	tm = sendKeys(tm, "down", "enter")
+	b := tm.(browser)
+	if b.doc != nil || rowPaths(b) != "README.md,docs" || !strings.Contains(stripANSI(b.View()), "▸ docs/") {
+		t.Fatalf("expected enter to fold docs, got %q", rowPaths(b))
+	}
+	tm = sendKeys(tm, "l", "down", "down")
+	if b = tm.(browser); rowPaths(b) != "README.md,docs,docs/api.md,docs/guide.md" || b.rows[b.cursor].path != "docs/guide.md" {
+		t.Fatalf("expected l to unfold docs, got %q", rowPaths(b))
+	}
+	// Left on a file folds its directory and selects it.
+	tm = sendKeys(tm, "left")
+	if b = tm.(browser); rowPaths(b) != "README.md,docs" || b.rows[b.cursor].path != "docs" {
+		t.Errorf("expected left to fold the file's directory, got %q at %d", rowPaths(b), b.cursor)
+	}
+}
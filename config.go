@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// configName is the per-project config file, looked up from the document's
+// directory towards the filesystem root.
+const configName = ".incipit.yaml"
+
+// config holds the per-project settings read from configName.
+type config struct {
+	extensions  []string // opt-in syntax extensions, see knownExtensions
+	maxWidth    int      // reading width in columns, 0 for none
+	lintDisable []string // lint rules to skip, see lintRules
+}
+
+// findConfig returns the path of the nearest configName at or above dir, or ""
+// when there is none.
+func findConfig(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, configName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// parseConfig reads config settings from the YAML text of a config file.
+func parseConfig(data string) (config, error) {
+	var c config
+	for _, f := range parseYAMLFields(strings.Split(data, "\n")) {
+		switch f.key {
+		case "extensions":
+			for _, name := range strings.Split(f.value, ",") {
+				name = strings.TrimSpace(name)
+				if name == "" {
+					continue
+				}
+				if !slices.Contains(knownExtensions, name) {
+					return c, fmt.Errorf("unknown extension %q (known: %s)", name, strings.Join(knownExtensions, ", "))
+				}
+				c.extensions = append(c.extensions, name)
+			}
+		case "max-width":
+			n, err := strconv.Atoi(f.value)
+			if err != nil || n <= 0 {
+				return c, fmt.Errorf("max-width must be a positive number of columns, got %q", f.value)
+			}
+			c.maxWidth = n
+		case "lint-disable":
+			c.lintDisable = splitList(f.value)
+			if err := checkLintRules(c.lintDisable); err != nil {
+				return c, err
+			}
+		}
+	}
+	return c, nil
+}
+
+// loadConfig reads the project config for a document in dir. A missing config
+// file yields the zero config.
+func loadConfig(dir string) (config, error) {
+	path := findConfig(dir)
+	if path == "" {
+		return config{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config{}, err
+	}
+	c, err := parseConfig(string(data))
+	if err != nil {
+		return config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// enabled reports whether the named extension is switched on.
+func (c config) enabled(name string) bool {
+	return slices.Contains(c.extensions, name)
+}
+
+// apply copies the config's settings into opts.
+func (c config) apply(opts *renderOptions) {
+	opts.definitionLists = c.enabled(extDefinitionLists)
+	opts.abbreviations = c.enabled(extAbbreviations)
+	opts.emoji = c.enabled(extEmoji)
+}
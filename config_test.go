@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig_Extensions(t *testing.T) {
+	c, err := parseConfig("# project settings\nextensions: [definition-lists, emoji]\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.enabled(extDefinitionLists) || !c.enabled(extEmoji) || c.enabled(extAbbreviations) {
+		t.Errorf("unexpected extensions: %v", c.extensions)
+	}
+	var opts renderOptions
+	c.apply(&opts)
+	if !opts.definitionLists || !opts.emoji || opts.abbreviations {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+}
+
+func TestParseConfig_BlockList(t *testing.T) {
+	c, err := parseConfig("extensions:\n  - abbreviations\n")
+	if err != nil || !c.enabled(extAbbreviations) {
+		t.Errorf("expected block list parsed, got %v, %v", c.extensions, err)
+	}
+}
+
+func TestParseConfig_UnknownExtension(t *testing.T) {
+	if _, err := parseConfig("extensions: [tables-of-doom]\n"); err == nil {
+		t.Error("expected error for unknown extension")
+	}
+}
+
+func TestLoadConfig_FindsNearestParent(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "docs", "spec")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, configName), []byte("extensions: [abbreviations]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := loadConfig(sub)
+	if err != nil || !c.enabled(extAbbreviations) {
+		t.Errorf("expected config from parent directory, got %v, %v", c.extensions, err)
+	}
+}
+
+func TestParseConfig_MaxWidth(t *testing.T) {
+	c, err := parseConfig("max-width: 96\n")
+	if err != nil || c.maxWidth != 96 {
+		t.Errorf("expected max-width 96, got %d, %v", c.maxWidth, err)
+	}
+	if _, err := parseConfig("max-width: wide\n"); err == nil {
+		t.Error("expected error for a non-numeric max-width")
+	}
+}
+
+func TestParseConfig_LintDisable(t *testing.T) {
+	c, err := parseConfig("lint-disable: bare-url, list-marker\n")
+	if err != nil || strings.Join(c.lintDisable, ",") != "bare-url,list-marker" {
+		t.Errorf("unexpected config %v (%v)", c.lintDisable, err)
+	}
+	if _, err := parseConfig("lint-disable: nope\n"); err == nil {
+		t.Error("expected an unknown rule to be an error")
+	}
+}
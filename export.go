@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	emoji "github.com/yuin/goldmark-emoji"
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// xtermBase holds the 16 standard xterm colors.
+var xtermBase = [16]string{
+	"#000000", "#800000", "#008000", "#808000", "#000080", "#800080", "#008080", "#c0c0c0",
+	"#808080", "#ff0000", "#00ff00", "#ffff00", "#0000ff", "#ff00ff", "#00ffff", "#ffffff",
+}
+
+// xtermHex returns the hex RGB of a 256-color index such as "57", or "" when
+// index is not one.
+func xtermHex(index string) string {
+	n, err := strconv.Atoi(index)
+	switch {
+	case err != nil || n < 0 || n > 255:
+		return ""
+	case n < 16:
+		return xtermBase[n]
+	case n < 232:
+		levels := [6]int{0, 95, 135, 175, 215, 255}
+		n -= 16
+		return fmt.Sprintf("#%02x%02x%02x", levels[n/36], levels[n/6%6], levels[n%6])
+	default:
+		g := 8 + 10*(n-232)
+		return fmt.Sprintf("#%02x%02x%02x", g, g, g)
+	}
+}
+
+// pageColors returns the 256-color indices of the page background, body text
+// and links for exported documents.
+func pageColors(style string) (bg, fg, link string) {
+	if style == "light" {
+		return "231", "235", "27"
+	}
+	return "234", "252", "39"
+}
+
+// htmlRenderer draws headings as pills and code blocks in bordered frames,
+// with mermaid diagrams drawn as in the terminal, leaving everything else to
+// goldmark's HTML renderer.
+type htmlRenderer struct {
+	style   string
+	headers []headerBlock // every heading in document order, numbered and anchored
+	next    int           // index in headers of the next heading rendered
+}
+
+func (r *htmlRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(gast.KindHeading, r.renderHeading)
+	reg.Register(gast.KindFencedCodeBlock, r.renderCodeBlock)
+	reg.Register(gast.KindCodeBlock, r.renderCodeBlock)
+}
+
+func (r *htmlRenderer) renderHeading(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
+	n := node.(*gast.Heading)
+	if !entering {
+		fmt.Fprintf(w, "</span></h%d>\n", n.Level)
+		return gast.WalkContinue, nil
+	}
+	var h headerBlock
+	if r.next < len(r.headers) {
+		h = r.headers[r.next]
+		r.next++
+	}
+	fmt.Fprintf(w, `<h%d id="%s"><span class="pill">`, n.Level, html.EscapeString(h.anchor))
+	if h.number != "" {
+		fmt.Fprintf(w, `<span class="number">%s</span> `, h.number)
+	}
+	return gast.WalkContinue, nil
+}
+
+func (r *htmlRenderer) renderCodeBlock(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
+	if !entering {
+		return gast.WalkSkipChildren, nil
+	}
+	lang := ""
+	if n, ok := node.(*gast.FencedCodeBlock); ok {
+		lang = string(n.Language(source))
+	}
+	var code strings.Builder
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		seg := lines.At(i)
+		code.Write(seg.Value(source))
+	}
+	w.WriteString(`<figure class="code-block">`)
+	if lang != "" {
+		fmt.Fprintf(w, "<figcaption>%s</figcaption>", html.EscapeString(lang))
+	}
+	diagram, ok := "", false
+	if lang == "mermaid" {
+		diagram, ok = renderMermaid(code.String())
+	}
+	if ok {
+		fmt.Fprintf(w, `<pre class="diagram">%s</pre>`, html.EscapeString(diagram))
+	} else {
+		w.WriteString(highlightHTML(code.String(), lang, r.style))
+	}
+	w.WriteString("</figure>\n")
+	return gast.WalkSkipChildren, nil
+}
+
+// highlightHTML returns code as a chroma-highlighted <pre> block using CSS
+// classes from exportCSS.
+func highlightHTML(code, lang, style string) string {
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+	var buf bytes.Buffer
+	it, err := lexer.Tokenise(nil, code)
+	if err == nil {
+		err = chromahtml.New(chromahtml.WithClasses(true)).Format(&buf, styles.Get(chromaStyleName(style)), it)
+	}
+	if err != nil {
+		return "<pre><code>" + html.EscapeString(code) + "</code></pre>"
+	}
+	return buf.String()
+}
+
+// mapOutsideFences applies fn to each run of lines of md outside fenced code
+// blocks.
+func mapOutsideFences(md string, fn func(string) string) string {
+	var out, run []string
+	flush := func() {
+		if len(run) > 0 {
+			out = append(out, fn(strings.Join(run, "\n")))
+			run = nil
+		}
+	}
+	fence := ""
+	for _, line := range strings.Split(md, "\n") {
+		sub := fenceRe.FindStringSubmatch(line)
+		switch {
+		case fence == "" && sub != nil:
+			flush()
+			fence = sub[1]
+			out = append(out, line)
+		case fence != "":
+			if sub != nil && sub[1] == fence {
+				fence = ""
+			}
+			out = append(out, line)
+		default:
+			run = append(run, line)
+		}
+	}
+	flush()
+	return strings.Join(out, "\n")
+}
+
+// exportMath converts the math in md for goldmark: inline math becomes its
+// Unicode rendering, and display math a preformatted block.
+func exportMath(md string) string {
+	prose, blocks := extractMath(md)
+	for i, tex := range blocks {
+		if out, ok := texToUnicode(tex); ok {
+			tex = out
+		}
+		block := fmt.Sprintf(`<pre class="math">%s</pre>`, html.EscapeString(tex))
+		prose = strings.Replace(prose, fmt.Sprintf("INCIPIT_MATH_%d", i), block, 1)
+	}
+	return prose
+}
+
+// exportAlerts turns the alerts in md into titled <div> blocks around their
+// markdown bodies.
+func exportAlerts(md string) string {
+	prose, alerts := extractAlerts(md)
+	for i, a := range alerts {
+		block := fmt.Sprintf("<div class=\"alert alert-%s\">\n<p class=\"alert-title\">%s %s</p>\n\n%s\n\n</div>",
+			a.kind, alertIcons[a.kind], html.EscapeString(a.title), a.body)
+		prose = strings.Replace(prose, fmt.Sprintf("INCIPIT_ALERT_%d", i), block, 1)
+	}
+	return prose
+}
+
+// exportPolicy returns the sanitizer applied to an exported document: the
+// raw HTML markdown allows in user content, plus the classes of incipit's own
+// markup and task list checkboxes.
+func exportPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.RequireNoFollowOnLinks(false)
+	p.AllowElements("kbd")
+	p.AllowAttrs("class", "id").Globally()
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
+
+// documentHeaders returns every heading of doc in order, as extractHeaders
+// would, with numbers and anchors filled in.
+func documentHeaders(doc gast.Node, source []byte, numbered bool) []headerBlock {
+	var headers []headerBlock
+	_ = gast.Walk(doc, func(n gast.Node, entering bool) (gast.WalkStatus, error) {
+		if h, ok := n.(*gast.Heading); ok && entering {
+			text := strings.TrimSpace(string(h.Lines().Value(source)))
+			headers = append(headers, headerBlock{level: h.Level, text: text})
+			return gast.WalkSkipChildren, nil
+		}
+		return gast.WalkContinue, nil
+	})
+	if numbered {
+		numberHeadings(headers)
+	}
+	anchorHeadings(headers)
+	return headers
+}
+
+// exportCSS returns the stylesheet of an exported page, built from the same
+// palettes the terminal renderer uses.
+func exportCSS(style string) string {
+	bg, fg, link := pageColors(style)
+	codeBg, border := codeBlockColors(style)
+	var b strings.Builder
+	fmt.Fprintf(&b, `body { margin: 0; background: %s; color: %s; }
+main { max-width: 50rem; margin: 0 auto; padding: 2rem 1rem;
+  font: 16px/1.6 ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; }
+a { color: %s; }
+h1, h2, h3, h4, h5, h6 { font-size: 1em; margin: 1.6em 0 0.8em; }
+.pill { display: inline-block; padding: 0 2ch; }
+.code-block { margin: 1em 0; border: 1px solid %s; border-radius: 6px; background: %s; }
+.code-block figcaption { padding: 0.2em 1ch; color: %s; border-bottom: 1px solid %s; }
+.code-block pre { margin: 0; padding: 1em 1ch; overflow-x: auto; background: %s !important; }
+.front-matter { border: 1px solid %s; border-radius: 6px; padding: 0.5em 1ch; }
+.front-matter th { text-align: left; padding-right: 2ch; color: %s; }
+blockquote { margin-left: 0; padding-left: 1em; border-left: 2px solid %s; }
+table { border-collapse: collapse; }
+th, td { padding: 0.2em 1ch; border-bottom: 1px solid %s; }
+.code-block pre.diagram { line-height: 1.2; }
+.math { text-align: center; }
+.alert { margin: 1em 0; padding: 0 1ch; border: 1px solid; border-radius: 6px; }
+.alert-title { font-weight: bold; }
+`, xtermHex(bg), xtermHex(fg), xtermHex(link),
+		xtermHex(border), xtermHex(codeBg), xtermHex(border), xtermHex(border), xtermHex(codeBg),
+		xtermHex(border), xtermHex(headerFg(2, style)), xtermHex(border), xtermHex(border))
+	for _, kind := range []string{"note", "tip", "important", "warning", "caution"} {
+		color := xtermHex(alertColor(kind, style))
+		fmt.Fprintf(&b, ".alert-%s { border-color: %s; } .alert-%s .alert-title { color: %s; }\n", kind, color, kind, color)
+	}
+	for level := 1; level <= 6; level++ {
+		hfg, hbg, bold := headerColors(level, style)
+		weight := "normal"
+		if bold {
+			weight = "bold"
+		}
+		fmt.Fprintf(&b, "h%d .pill { color: %s; background: %s; font-weight: %s; }\n", level, xtermHex(hfg), xtermHex(hbg), weight)
+	}
+	_ = chromahtml.New(chromahtml.WithClasses(true)).WriteCSS(&b, styles.Get(chromaStyleName(style)))
+	return b.String()
+}
+
+// headerFg returns the foreground color index of a heading level.
+func headerFg(level int, style string) string {
+	fg, _, _ := headerColors(level, style)
+	return fg
+}
+
+// renderFrontMatterHTML renders front matter as a key/value table.
+func renderFrontMatterHTML(fm *frontMatter) string {
+	if fm == nil || len(fm.fields) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(`<table class="front-matter">` + "\n")
+	for _, f := range fm.fields {
+		fmt.Fprintf(&b, "<tr><th>%s</th><td>%s</td></tr>\n", html.EscapeString(f.key), html.EscapeString(f.value))
+	}
+	b.WriteString("</table>\n")
+	return b.String()
+}
+
+// exportHTML renders a file's contents as a standalone HTML page in the given
+// theme. Anything but "light" gets the dark palette. Raw HTML in the document
+// is sanitized, so scripts and event handlers do not reach the page.
+func exportHTML(content, filename, style string, opts renderOptions) (string, error) {
+	if style != "light" {
+		style = "dark"
+	}
+	body, fm := extractFrontMatter(content)
+	source := []byte(mapOutsideFences(body, func(s string) string { return exportAlerts(exportMath(s)) }))
+
+	exts := []goldmark.Extender{extension.GFM, extension.Footnote}
+	if opts.definitionLists {
+		exts = append(exts, extension.DefinitionList)
+	}
+	if opts.emoji {
+		exts = append(exts, emoji.Emoji)
+	}
+	md := goldmark.New(goldmark.WithExtensions(exts...), goldmark.WithRendererOptions(gmhtml.WithUnsafe()))
+	doc := md.Parser().Parse(text.NewReader(source))
+	r := &htmlRenderer{style: style, headers: documentHeaders(doc, source, opts.numberHeadings)}
+	md.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(r, 100)))
+
+	var out bytes.Buffer
+	if err := md.Renderer().Render(&out, source, doc); err != nil {
+		return "", err
+	}
+
+	title := fm.get("title")
+	if title == "" {
+		title = filepath.Base(filename)
+	}
+	var page strings.Builder
+	page.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	page.WriteString(`<meta name="viewport" content="width=device-width, initial-scale=1">` + "\n")
+	fmt.Fprintf(&page, "<title>%s</title>\n<style>\n%s</style>\n</head>\n<body>\n<main>\n", html.EscapeString(title), exportCSS(style))
+	page.WriteString(renderFrontMatterHTML(fm))
+	page.Write(exportPolicy().SanitizeBytes(out.Bytes()))
+	page.WriteString("</main>\n</body>\n</html>\n")
+	return page.String(), nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestXtermHex(t *testing.T) {
+	cases := map[string]string{
+		"0":   "#000000",
+		"9":   "#ff0000",
+		"16":  "#000000",
+		"57":  "#5f00ff",
+		"231": "#ffffff",
+		"235": "#262626",
+		"255": "#eeeeee",
+		"256": "",
+		"red": "",
+	}
+	for in, want := range cases {
+		if got := xtermHex(in); got != want {
+			t.Errorf("xtermHex(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestExportHTML_Page(t *testing.T) {
+	md := "---\ntitle: Guide\n---\n# Intro\n\nSome *text*.\n\n## Usage\n\n## Usage\n"
+	out, err := exportHTML(md, "docs/guide.md", "dark", renderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"<title>Guide</title>",
+		`<h1 id="intro"><span class="pill">Intro</span></h1>`,
+		`<h2 id="usage-1">`,
+		"<em>text</em>",
+		`<table class="front-matter">`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in page", want)
+		}
+	}
+}
+
+func TestExportHTML_ThemeColors(t *testing.T) {
+	for _, style := range []string{"dark", "light"} {
+		out, _ := exportHTML("# Title\n", "doc.md", style, renderOptions{})
+		fg, bg, _ := headerColors(1, style)
+		want := "h1 .pill { color: " + xtermHex(fg) + "; background: " + xtermHex(bg)
+		if !strings.Contains(out, want) {
+			t.Errorf("%s: expected heading pill colors %q", style, want)
+		}
+		codeBg, _ := codeBlockColors(style)
+		if !strings.Contains(out, "background: "+xtermHex(codeBg)+" !important") {
+			t.Errorf("%s: expected the code block background in the stylesheet", style)
+		}
+	}
+}
+
+func TestExportHTML_CodeBlock(t *testing.T) {
+	out, _ := exportHTML("```go\nfunc main() {}\n```\n", "doc.md", "dark", renderOptions{})
+	if !strings.Contains(out, `<figure class="code-block"><figcaption>go</figcaption><pre class="chroma">`) {
+		t.Errorf("expected a framed chroma code block, got %q", out)
+	}
+	if !strings.Contains(out, `<span class="kd">func</span>`) {
+		t.Error("expected chroma token classes in the code block")
+	}
+}
+
+func TestExportHTML_NumberedHeadings(t *testing.T) {
+	out, _ := exportHTML("# A\n\n## B\n", "doc.md", "dark", renderOptions{numberHeadings: true})
+	if !strings.Contains(out, `<span class="number">1.1</span> B`) {
+		t.Errorf("expected numbered headings, got %q", out)
+	}
+}
+
+func TestExportHTML_SanitizesRawHTML(t *testing.T) {
+	md := "# Title\n\n<script>alert(1)</script>\n\n<img src=\"x.png\" onerror=\"alert(1)\">\n\nPress <kbd>q</kbd>.\n\n- [x] done\n"
+	out, _ := exportHTML(md, "doc.md", "dark", renderOptions{})
+	main := out[strings.Index(out, "<main>"):]
+	for _, bad := range []string{"<script", "alert(1)", "onerror"} {
+		if strings.Contains(main, bad) {
+			t.Errorf("expected %q removed, got %q", bad, main)
+		}
+	}
+	for _, want := range []string{`<img src="x.png">`, "<kbd>q</kbd>", `<input checked="" disabled="" type="checkbox">`, `<h1 id="title">`} {
+		if !strings.Contains(main, want) {
+			t.Errorf("expected %q kept, got %q", want, main)
+		}
+	}
+}
+
+func TestExportHTML_AlertsMathAndDiagrams(t *testing.T) {
+	md := "> [!WARNING]\n> Mind $x^2$.\n\n$$\n\\alpha\n$$\n\n```mermaid\ngraph LR\nA-->B\n```\n\n```\n> [!NOTE]\n```\n"
+	out, _ := exportHTML(md, "doc.md", "dark", renderOptions{})
+	for _, want := range []string{
+		`<div class="alert alert-warning">`,
+		`<p class="alert-title">⚠ Warning</p>`,
+		"<p>Mind x².</p>",
+		`<pre class="math">α</pre>`,
+		`<pre class="diagram">┌───┐`,
+		"&gt; [!NOTE]",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in page", want)
+		}
+	}
+}
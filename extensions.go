@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/yuin/goldmark"
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Names of the opt-in syntax extensions, as written in the project config.
+const (
+	extDefinitionLists = "definition-lists"
+	extAbbreviations   = "abbreviations"
+	extEmoji           = "emoji"
+)
+
+// knownExtensions lists every extension name the config accepts.
+var knownExtensions = []string{extDefinitionLists, extAbbreviations, extEmoji}
+
+// abbrDefRe matches a PHP Markdown Extra abbreviation definition, e.g.
+// "*[HTML]: Hyper Text Markup Language". Group 1 = abbreviation, group 2 = expansion.
+var abbrDefRe = regexp.MustCompile(`^ {0,3}\*\[([^\]]+)\]:\s*(.*?)\s*$`)
+
+// defListPlaceholderRe matches an INCIPIT_DEFLIST_N placeholder. Group 1 = index.
+var defListPlaceholderRe = regexp.MustCompile(`INCIPIT_DEFLIST_(\d+)`)
+
+type definitionEntry struct {
+	terms       []string
+	definitions []string // markdown source of each definition
+}
+
+type definitionList struct {
+	entries []definitionEntry
+}
+
+// deflistParser parses definition lists the way PHP Markdown Extra does.
+var deflistParser = goldmark.New(goldmark.WithExtensions(extension.DefinitionList)).Parser()
+
+// blockSpan returns the byte range of source covered by the lines of n and its
+// descendants.
+func blockSpan(n gast.Node) (start, stop int, ok bool) {
+	start, stop = -1, -1
+	_ = gast.Walk(n, func(c gast.Node, entering bool) (gast.WalkStatus, error) {
+		if !entering || c.Type() != gast.TypeBlock {
+			return gast.WalkContinue, nil
+		}
+		lines := c.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			seg := lines.At(i)
+			if start < 0 || seg.Start < start {
+				start = seg.Start
+			}
+			stop = max(stop, seg.Stop)
+		}
+		return gast.WalkContinue, nil
+	})
+	return start, stop, start >= 0
+}
+
+// blockText returns the text of each line of n's block children joined into
+// paragraphs.
+func blockText(n gast.Node, source []byte) string {
+	var paras []string
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		lines := c.Lines()
+		var parts []string
+		for i := 0; i < lines.Len(); i++ {
+			seg := lines.At(i)
+			parts = append(parts, strings.TrimSpace(string(seg.Value(source))))
+		}
+		if len(parts) > 0 {
+			paras = append(paras, strings.Join(parts, "\n"))
+		}
+	}
+	return strings.Join(paras, "\n\n")
+}
+
+// extractDefinitionLists pulls definition lists out of md, replacing each with
+// a unique placeholder paragraph, and returns the modified prose plus the lists.
+func extractDefinitionLists(md string) (string, []definitionList) {
+	source := []byte(md)
+	doc := deflistParser.Parse(text.NewReader(source))
+
+	type span struct{ first, last int }
+	var spans []span
+	var lists []definitionList
+	lineOf := func(offset int) int { return strings.Count(md[:offset], "\n") }
+
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		if n.Kind() != extast.KindDefinitionList {
+			continue
+		}
+		start, stop, ok := blockSpan(n)
+		if !ok {
+			continue
+		}
+		var dl definitionList
+		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+			switch c.Kind() {
+			case extast.KindDefinitionTerm:
+				term := strings.TrimSpace(string(c.Lines().Value(source)))
+				if len(dl.entries) == 0 || len(dl.entries[len(dl.entries)-1].definitions) > 0 {
+					dl.entries = append(dl.entries, definitionEntry{})
+				}
+				e := &dl.entries[len(dl.entries)-1]
+				e.terms = append(e.terms, term)
+			case extast.KindDefinitionDescription:
+				if len(dl.entries) == 0 {
+					dl.entries = append(dl.entries, definitionEntry{})
+				}
+				e := &dl.entries[len(dl.entries)-1]
+				e.definitions = append(e.definitions, blockText(c, source))
+			}
+		}
+		spans = append(spans, span{lineOf(start), lineOf(max(stop-1, start))})
+		lists = append(lists, dl)
+	}
+	if len(lists) == 0 {
+		return md, nil
+	}
+
+	lines := strings.Split(md, "\n")
+	for i := len(spans) - 1; i >= 0; i-- {
+		s := spans[i]
+		placeholder := fmt.Sprintf("\nINCIPIT_DEFLIST_%d\n", i)
+		lines = append(lines[:s.first], append([]string{placeholder}, lines[s.last+1:]...)...)
+	}
+	return strings.Join(lines, "\n"), lists
+}
+
+// renderDefinitionTerm renders a definition term bold in the theme's color.
+func renderDefinitionTerm(term, style string) string {
+	term = stripInlineMarkdown(term)
+	if style == "notty" {
+		return term
+	}
+	fg, _, _ := headerColors(3, style)
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(fg)).Bold(true).Render(term)
+}
+
+// renderDefinitionList renders terms at the paragraph margin and their
+// definitions indented beneath them, rendered with the document's options.
+func renderDefinitionList(dl definitionList, width int, style string, opts renderOptions) string {
+	const margin, indent = 2, 6
+	var out []string
+	for i, e := range dl.entries {
+		if i > 0 {
+			out = append(out, "")
+		}
+		for _, term := range e.terms {
+			out = append(out, strings.Repeat(" ", margin)+renderDefinitionTerm(term, style))
+		}
+		for _, def := range e.definitions {
+			body := renderDocument(def, style, max(width-indent, 10), nestedOptions(opts))
+			for _, line := range trimBlankLines(strings.Split(body, "\n")) {
+				out = append(out, strings.Repeat(" ", indent)+ansi.TruncateLeft(line, margin, ""))
+			}
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// injectDefinitionLists replaces INCIPIT_DEFLIST_N placeholder lines in
+// rendered with the rendered definition list.
+func injectDefinitionLists(rendered string, lists []definitionList, width int, style string, opts renderOptions) string {
+	lines := strings.Split(rendered, "\n")
+	for i, line := range lines {
+		sub := defListPlaceholderRe.FindStringSubmatch(stripANSI(line))
+		if sub == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(sub[1])
+		if n < len(lists) {
+			lines[i] = renderDefinitionList(lists[n], width, style, opts)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// expandAbbreviations removes abbreviation definitions from md and spells out
+// each abbreviation after its first use in running text, e.g.
+// "HTML (Hyper Text Markup Language)". Headings and code spans are skipped.
+func expandAbbreviations(md string) string {
+	defs := map[string]string{}
+	var body []string
+	for _, line := range strings.Split(md, "\n") {
+		if sub := abbrDefRe.FindStringSubmatch(line); sub != nil {
+			defs[sub[1]] = sub[2]
+			continue
+		}
+		body = append(body, line)
+	}
+	if len(defs) == 0 {
+		return md
+	}
+
+	// Longest first, so "HTML5" is tried before "HTML".
+	abbrs := make([]string, 0, len(defs))
+	for a := range defs {
+		abbrs = append(abbrs, a)
+	}
+	sort.Slice(abbrs, func(i, j int) bool { return len(abbrs[i]) > len(abbrs[j]) })
+	for _, abbr := range abbrs {
+		re := regexp.MustCompile(`(^|[^\pL\pN_])(` + regexp.QuoteMeta(abbr) + `)($|[^\pL\pN_])`)
+		done := false
+		for i, line := range body {
+			if done {
+				break
+			}
+			if headerRe.MatchString(line) || strings.Contains(line, "INCIPIT_") {
+				continue
+			}
+			body[i] = mapOutsideCodeSpans(line, func(s string) string {
+				if done {
+					return s
+				}
+				loc := re.FindStringSubmatchIndex(s)
+				if loc == nil {
+					return s
+				}
+				done = true
+				return s[:loc[5]] + " (" + defs[abbr] + ")" + s[loc[5]:]
+			})
+		}
+	}
+	return strings.Join(body, "\n")
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// extractDefinitionLists tests
+
+func TestExtractDefinitionLists_TermsAndDefinitions(t *testing.T) {
+	md := "Intro.\n\nApple\nPear\n: Fruit.\n: Grows on trees.\n\nOrange\n: Citrus.\n\nOutro."
+	prose, lists := extractDefinitionLists(md)
+	if len(lists) != 1 {
+		t.Fatalf("expected 1 definition list, got %d", len(lists))
+	}
+	entries := lists[0].entries
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+	if strings.Join(entries[0].terms, ",") != "Apple,Pear" || len(entries[0].definitions) != 2 {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].terms[0] != "Orange" || entries[1].definitions[0] != "Citrus." {
+		t.Errorf("unexpected second entry: %+v", entries[1])
+	}
+	if !strings.Contains(prose, "Intro.") || !strings.Contains(prose, "Outro.") || !strings.Contains(prose, "INCIPIT_DEFLIST_0") {
+		t.Errorf("unexpected prose %q", prose)
+	}
+	if strings.Contains(prose, "Citrus") {
+		t.Errorf("expected list source removed, got %q", prose)
+	}
+}
+
+func TestExtractDefinitionLists_None(t *testing.T) {
+	md := "Just a paragraph.\n\n- a list\n"
+	if prose, lists := extractDefinitionLists(md); prose != md || lists != nil {
+		t.Errorf("expected input unchanged, got %q %+v", prose, lists)
+	}
+}
+
+func TestRenderDefinitionList_IndentsDefinitions(t *testing.T) {
+	dl := definitionList{entries: []definitionEntry{{terms: []string{"**Term**"}, definitions: []string{"Meaning."}}}}
+	lines := strings.Split(stripANSI(renderDefinitionList(dl, 40, "notty", renderOptions{})), "\n")
+	if lines[0] != "  Term" {
+		t.Errorf("expected term at the margin without markers, got %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "      Meaning.") {
+		t.Errorf("expected indented definition, got %q", lines[1])
+	}
+}
+
+// expandAbbreviations tests
+
+func TestExpandAbbreviations_FirstUseOnly(t *testing.T) {
+	md := "# HTML\n\nUse `HTML` and HTML5 and HTML here, HTML there.\n\n*[HTML]: Hyper Text Markup Language\n"
+	got := expandAbbreviations(md)
+	if !strings.Contains(got, "HTML5 and HTML (Hyper Text Markup Language) here, HTML there.") {
+		t.Errorf("expected first whole-word use expanded, got %q", got)
+	}
+	if !strings.HasPrefix(got, "# HTML\n") || !strings.Contains(got, "`HTML`") {
+		t.Errorf("expected headings and code spans untouched, got %q", got)
+	}
+	if strings.Contains(got, "*[HTML]") {
+		t.Errorf("expected definition removed, got %q", got)
+	}
+}
+
+// end-to-end tests
+
+func TestRenderDocument_ExtensionsAreOptIn(t *testing.T) {
+	md := "Term\n: Definition.\n\nThe W3C site.\n\n*[W3C]: World Wide Web Consortium\n"
+	off := stripANSI(renderDocument(md, "dark", 60, renderOptions{}))
+	if !strings.Contains(off, "*[W3C]") {
+		t.Errorf("expected abbreviation syntax left alone when disabled, got %q", off)
+	}
+	on := stripANSI(renderDocument(md, "dark", 60, renderOptions{definitionLists: true, abbreviations: true}))
+	for _, want := range []string{"  Term", "      Definition.", "W3C (World Wide Web Consortium)"} {
+		if !strings.Contains(on, want) {
+			t.Errorf("expected %q in output, got %q", want, on)
+		}
+	}
+}
+
+func TestRenderDocument_Emoji(t *testing.T) {
+	out := stripANSI(renderDocument("Nice :smile:", "dark", 60, renderOptions{emoji: true}))
+	if !strings.Contains(out, "😄") {
+		t.Errorf("expected emoji shortcode replaced, got %q", out)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// isMarkdownFile reports whether name has a markdown extension.
+func isMarkdownFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// ignoreRule is one pattern of a .gitignore file.
+type ignoreRule struct {
+	dir     string // slash-separated directory of the .gitignore, relative to the walk root
+	re      *regexp.Regexp
+	negate  bool // "!pattern" re-includes paths
+	dirOnly bool // "pattern/" only matches directories
+}
+
+// globToRegexp translates a gitignore glob into a regexp over slash-separated
+// paths. Patterns with a slash other than a trailing one are anchored to
+// their .gitignore's directory; others match a name at any depth.
+func globToRegexp(glob string) *regexp.Regexp {
+	anchored := strings.Contains(glob, "/")
+	glob = strings.TrimPrefix(glob, "/")
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			if end := strings.IndexByte(glob[i:], ']'); end > 0 {
+				b.WriteString(strings.Replace(glob[i:i+end+1], "[!", "[^", 1))
+				i += end
+			} else {
+				b.WriteString(`\[`)
+			}
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	// A matching directory ignores everything inside it.
+	b.WriteString("(?:/.*)?$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return regexp.MustCompile(`^\b$`) // matches nothing
+	}
+	return re
+}
+
+// parseGitignore returns the rules of a .gitignore in dir.
+func parseGitignore(data, dir string) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, " \r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r := ignoreRule{dir: dir}
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		r.re = globToRegexp(line)
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// ignored reports whether the slash-separated path rel, relative to the walk
+// root, is ignored by rules. Later rules override earlier ones.
+func ignored(rules []ignoreRule, rel string, isDir bool) bool {
+	ignore := false
+	for _, r := range rules {
+		p := rel
+		if r.dir != "" {
+			if !strings.HasPrefix(rel, r.dir+"/") {
+				continue
+			}
+			p = strings.TrimPrefix(rel, r.dir+"/")
+		}
+		if r.dirOnly && !isDir {
+			continue // files in ignored directories are never walked
+		}
+		if r.re.MatchString(p) {
+			ignore = !r.negate
+		}
+	}
+	return ignore
+}
+
+// markdownFiles returns the markdown files under root in lexical order,
+// skipping hidden directories and anything the .gitignore files along the
+// way exclude.
+func markdownFiles(root string) ([]string, error) {
+	var files []string
+	var rules []ignoreRule
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(d.Name(), ".") || ignored(rules, rel, true)) {
+				return filepath.SkipDir
+			}
+			if data, err := os.ReadFile(filepath.Join(path, ".gitignore")); err == nil {
+				dir := rel
+				if dir == "." {
+					dir = ""
+				}
+				rules = append(rules, parseGitignore(string(data), dir)...)
+			}
+			return nil
+		}
+		if isMarkdownFile(path) && !ignored(rules, rel, false) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIgnored(t *testing.T) {
+	rules := parseGitignore("# comment\n*.tmp.md\n/draft.md\nbuild/\ndocs/**/old.md\n!keep.tmp.md\n", "")
+	rules = append(rules, parseGitignore("local.md\n", "sub")...)
+	cases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"notes.tmp.md", false, true},
+		{"a/b/notes.tmp.md", false, true},
+		{"keep.tmp.md", false, false},
+		{"draft.md", false, true},
+		{"a/draft.md", false, false},
+		{"build", true, true},
+		{"a/build", true, true},
+		{"build", false, false},
+		{"docs/x/y/old.md", false, true},
+		{"docs/old.md", false, true},
+		{"sub/local.md", false, true},
+		{"local.md", false, false},
+		{"README.md", false, false},
+	}
+	for _, c := range cases {
+		if got := ignored(rules, c.path, c.isDir); got != c.want {
+			t.Errorf("ignored(%q, %v) = %v, want %v", c.path, c.isDir, got, c.want)
+		}
+	}
+}
+
+func TestMarkdownFiles(t *testing.T) {
+	dir := writeDocs(t, map[string]string{
+		".gitignore":          "build/\n",
+		"README.md":           "",
+		"notes.txt":           "",
+		"docs/guide.md":       "",
+		"docs/.gitignore":     "draft.markdown\n",
+		"docs/draft.markdown": "",
+		"docs/api.markdown":   "",
+		"build/out.md":        "",
+		".github/issue.md":    "",
+	})
+	files, err := markdownFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "README.md"),
+		filepath.Join(dir, "docs", "api.markdown"),
+		filepath.Join(dir, "docs", "guide.md"),
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("got %q, want %q", files, want)
+	}
+}
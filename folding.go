@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// section is a heading's section of a rendered document, with the sections
+// nested in it.
+type section struct {
+	heading   int // index into the document's headings
+	line, end int // rendered lines of the heading and after the section's last line
+	children  []*section
+}
+
+// sectionTree builds the section tree of a rendered document of total lines
+// from its headings. A section runs to the next heading of the same or a
+// higher level, or to the end.
+func sectionTree(headings []heading, total int) []*section {
+	var roots, stack []*section
+	for i, h := range headings {
+		for len(stack) > 0 && headings[stack[len(stack)-1].heading].level >= h.level {
+			stack[len(stack)-1].end = h.line
+			stack = stack[:len(stack)-1]
+		}
+		s := &section{heading: i, line: h.line, end: total}
+		if len(stack) == 0 {
+			roots = append(roots, s)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, s)
+		}
+		stack = append(stack, s)
+	}
+	return roots
+}
+
+// body returns the range [start, end) of rendered lines folded away under s.
+// Blank lines closing the section stay visible so folded headings keep their
+// spacing.
+func (s *section) body(lines []string) (start, end int) {
+	start = s.line + 1
+	end = min(s.end, len(lines))
+	for end > start && strings.TrimSpace(stripANSI(lines[end-1])) == "" {
+		end--
+	}
+	return start, end
+}
+
+// renderFoldMarker renders the marker shown after a folded heading.
+func renderFoldMarker(n int, style string) string {
+	unit := "lines"
+	if n == 1 {
+		unit = "line"
+	}
+	marker := fmt.Sprintf(" ▸ (%d %s)", n, unit)
+	if style == "notty" {
+		return marker
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render(marker)
+}
+
+// foldContent hides the folded sections of rendered, marking each folded
+// heading with the number of lines hidden. Sections inside a folded one are
+// hidden with it. It returns the visible content and, for each visible line,
+// its index in rendered.
+func foldContent(rendered string, sections []*section, folded map[int]bool, style string) (string, []int) {
+	lines := strings.Split(rendered, "\n")
+	hidden := make([]bool, len(lines))
+	markers := map[int]string{}
+	var fold func([]*section)
+	fold = func(sections []*section) {
+		for _, s := range sections {
+			if !folded[s.heading] || s.line >= len(lines) {
+				fold(s.children)
+				continue
+			}
+			start, end := s.body(lines)
+			if end <= start {
+				continue
+			}
+			for j := start; j < end; j++ {
+				hidden[j] = true
+			}
+			markers[s.line] = renderFoldMarker(end-start, style)
+		}
+	}
+	fold(sections)
+
+	var out []string
+	var visible []int
+	for i, line := range lines {
+		if hidden[i] {
+			continue
+		}
+		out = append(out, line+markers[i])
+		visible = append(visible, i)
+	}
+	return strings.Join(out, "\n"), visible
+}
+
+// headingAt returns the index of the heading whose section holds rendered
+// line, or -1 when the line comes before the first heading.
+func headingAt(headings []heading, line int) int {
+	at := -1
+	for i, h := range headings {
+		if h.line > line {
+			break
+		}
+		at = i
+	}
+	return at
+}
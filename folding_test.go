@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const foldDoc = "# Guide\n\nIntro text.\n\n## Install\n\nRun the installer.\n\nThen restart.\n\n## Usage\n\nCall it.\n"
+
+func TestRenderOutline_HeadingLines(t *testing.T) {
+	out, headings, _ := renderOutline(foldDoc, "notty", 60, renderOptions{})
+	if len(headings) != 3 {
+		t.Fatalf("expected 3 headings, got %d", len(headings))
+	}
+	lines := strings.Split(stripANSI(out), "\n")
+	for _, h := range headings {
+		if !strings.Contains(lines[h.line], h.text) {
+			t.Errorf("expected heading %q on line %d, got %q", h.text, h.line, lines[h.line])
+		}
+	}
+	if headings[1].level != 2 || headings[1].text != "Install" {
+		t.Errorf("unexpected second heading %+v", headings[1])
+	}
+}
+
+func TestSectionTree(t *testing.T) {
+	headings := []heading{{level: 1, line: 0}, {level: 2, line: 4}, {level: 3, line: 6}, {level: 2, line: 9}, {level: 1, line: 12}}
+	roots := sectionTree(headings, 15)
+	if len(roots) != 2 || roots[0].end != 12 || roots[1].end != 15 {
+		t.Fatalf("unexpected top-level sections %+v", roots)
+	}
+	sub := roots[0].children
+	if len(sub) != 2 || sub[0].end != 9 || sub[1].end != 12 {
+		t.Fatalf("unexpected subsections %+v", sub)
+	}
+	if len(sub[0].children) != 1 || sub[0].children[0].heading != 2 || sub[0].children[0].end != 9 {
+		t.Errorf("unexpected nested section %+v", sub[0].children)
+	}
+}
+
+func TestFoldContent_HidesSection(t *testing.T) {
+	out, headings, _ := renderOutline(foldDoc, "notty", 60, renderOptions{})
+	sections := sectionTree(headings, strings.Count(out, "\n")+1)
+	folded, visible := foldContent(out, sections, map[int]bool{1: true}, "notty")
+	if strings.Contains(folded, "installer") || strings.Contains(folded, "restart") {
+		t.Errorf("expected the Install section hidden, got %q", folded)
+	}
+	if !strings.Contains(folded, "Install ▸ (4 lines)") {
+		t.Errorf("expected a fold marker on the Install heading, got %q", folded)
+	}
+	if !strings.Contains(folded, "Call it.") || !strings.Contains(folded, "Intro text.") {
+		t.Errorf("expected other sections kept, got %q", folded)
+	}
+	lines := strings.Split(out, "\n")
+	for row, line := range strings.Split(folded, "\n") {
+		if !strings.HasPrefix(line, lines[visible[row]]) {
+			t.Errorf("visible line %d maps to %q, shows %q", row, lines[visible[row]], line)
+		}
+	}
+}
+
+func TestFoldContent_NestedSections(t *testing.T) {
+	out, headings, _ := renderOutline(foldDoc, "notty", 60, renderOptions{})
+	sections := sectionTree(headings, strings.Count(out, "\n")+1)
+	folded, _ := foldContent(out, sections, map[int]bool{0: true, 1: true}, "notty")
+	if strings.Contains(folded, "Install") || strings.Contains(folded, "Usage") {
+		t.Errorf("expected subsections hidden under the folded top heading, got %q", folded)
+	}
+	if !strings.Contains(folded, "Guide ▸") {
+		t.Errorf("expected a fold marker on the top heading, got %q", folded)
+	}
+}
+
+func TestHeadingAt(t *testing.T) {
+	headings := []heading{{line: 2}, {line: 8}}
+	for line, want := range map[int]int{0: -1, 2: 0, 5: 0, 8: 1, 20: 1} {
+		if got := headingAt(headings, line); got != want {
+			t.Errorf("headingAt(%d) = %d, want %d", line, got, want)
+		}
+	}
+}
+
+// model tests
+
+func TestModel_FoldKeys(t *testing.T) {
+	var tm tea.Model = newModel("doc.md", foldDoc, "notty")
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 60, Height: 40})
+	press := func(keys string) {
+		for _, r := range keys {
+			tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+	}
+
+	press("zM")
+	view := tm.View()
+	if strings.Contains(view, "Intro text") || !strings.Contains(view, "Guide ▸") {
+		t.Fatalf("expected zM to fold everything, got %q", view)
+	}
+
+	press("za")
+	view = tm.View()
+	if !strings.Contains(view, "Intro text") || !strings.Contains(view, "Install ▸") {
+		t.Errorf("expected za to open the top section only, got %q", view)
+	}
+
+	press("zR")
+	view = tm.View()
+	if strings.Contains(view, "▸") || !strings.Contains(view, "installer") {
+		t.Errorf("expected zR to unfold everything, got %q", view)
+	}
+
+	press("a")
+	if strings.Contains(tm.View(), "▸") {
+		t.Error("expected a without z to leave folds alone")
+	}
+}
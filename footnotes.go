@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// footnoteDefRe matches a footnote definition line. Group 1 = label, group 2 = text.
+var footnoteDefRe = regexp.MustCompile(`^ {0,3}\[\^([^\]\s]+)\]:\s*(.*)$`)
+
+// footnoteRefRe matches a footnote reference. Group 1 = label.
+var footnoteRefRe = regexp.MustCompile(`\[\^([^\]\s]+)\]`)
+
+// footnoteRefMark tags the superscript number of each footnote reference
+// until the rendered line it ends up on has been recorded, so superscripts
+// from math or the text itself are not mistaken for references.
+const footnoteRefMark = "\u2063"
+
+// footnoteRefMarkRe matches a tagged reference number. Group 1 = number.
+var footnoteRefMarkRe = regexp.MustCompile(footnoteRefMark + `([⁰¹²³⁴⁵⁶⁷⁸⁹]+)`)
+
+// footnoteItemRe matches a rendered footnote definition. Group 1 = number.
+var footnoteItemRe = regexp.MustCompile(`^\s*(\d+)\.\s`)
+
+const footnotesPlaceholder = "INCIPIT_FOOTNOTES"
+
+type footnote struct {
+	label string
+	text  string
+}
+
+// extractFootnotes removes footnote definitions from md, replaces references
+// with tagged superscript numbers (in order of first reference), and appends the
+// definitions as a numbered list under a footnotes placeholder. Definitions
+// that are never referenced are dropped, and references without a definition
+// are left as written. It returns the modified prose plus the footnotes in
+// number order.
+func extractFootnotes(md string) (string, []footnote) {
+	defs := map[string]string{}
+	var body []string
+	lines := strings.Split(md, "\n")
+	for i := 0; i < len(lines); i++ {
+		sub := footnoteDefRe.FindStringSubmatch(lines[i])
+		if sub == nil {
+			body = append(body, lines[i])
+			continue
+		}
+		text := []string{sub[2]}
+		for i+1 < len(lines) && (strings.HasPrefix(lines[i+1], "    ") || strings.HasPrefix(lines[i+1], "\t")) {
+			text = append(text, strings.TrimSpace(lines[i+1]))
+			i++
+		}
+		defs[sub[1]] = strings.Join(text, " ")
+	}
+	if len(defs) == 0 {
+		return md, nil
+	}
+
+	var notes []footnote
+	numbers := map[string]int{}
+	for i, line := range body {
+		body[i] = mapOutsideCodeSpans(line, func(s string) string {
+			return footnoteRefRe.ReplaceAllStringFunc(s, func(ref string) string {
+				label := footnoteRefRe.FindStringSubmatch(ref)[1]
+				text, ok := defs[label]
+				if !ok {
+					return ref
+				}
+				n, seen := numbers[label]
+				if !seen {
+					notes = append(notes, footnote{label: label, text: text})
+					n = len(notes)
+					numbers[label] = n
+				}
+				return footnoteRefMark + superscriptNumber(n)
+			})
+		})
+	}
+
+	prose := strings.TrimRight(strings.Join(body, "\n"), "\n")
+	if len(notes) == 0 {
+		return prose + "\n", nil
+	}
+	var b strings.Builder
+	b.WriteString(prose)
+	b.WriteString("\n\n" + footnotesPlaceholder + "\n\n")
+	for i, fn := range notes {
+		fmt.Fprintf(&b, "%d. %s ↩\n", i+1, fn.text)
+	}
+	return b.String(), notes
+}
+
+// superscriptNumber returns n written with superscript digits.
+func superscriptNumber(n int) string {
+	var b strings.Builder
+	for _, r := range strconv.Itoa(n) {
+		b.WriteRune(superscripts[r])
+	}
+	return b.String()
+}
+
+// parseSuperscriptNumber is the inverse of superscriptNumber.
+func parseSuperscriptNumber(s string) (int, bool) {
+	var b strings.Builder
+	for _, r := range s {
+		found := false
+		for d, sup := range superscripts {
+			if sup == r && d >= '0' && d <= '9' {
+				b.WriteRune(d)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(b.String())
+	return n, err == nil
+}
+
+// mapOutsideCodeSpans applies fn to the parts of line that are not inside
+// backtick code spans.
+func mapOutsideCodeSpans(line string, fn func(string) string) string {
+	if !strings.Contains(line, "`") {
+		return fn(line)
+	}
+	var b strings.Builder
+	rest := line
+	for {
+		start := strings.Index(rest, "`")
+		if start < 0 {
+			b.WriteString(fn(rest))
+			return b.String()
+		}
+		n := len(rest[start:]) - len(strings.TrimLeft(rest[start:], "`"))
+		fence := rest[start : start+n]
+		end := strings.Index(rest[start+n:], fence)
+		if end < 0 {
+			b.WriteString(fn(rest))
+			return b.String()
+		}
+		b.WriteString(fn(rest[:start]))
+		b.WriteString(rest[start : start+n+end+n])
+		rest = rest[start+n+end+n:]
+	}
+}
+
+// renderFootnotesRule renders the rule that opens the footnotes section.
+func renderFootnotesRule(width int, style string) string {
+	title := "Footnotes"
+	rule := "── " + title + " " + strings.Repeat("─", max(0, width-len(title)-4))
+	if style == "notty" {
+		return rule
+	}
+	_, border := codeBlockColors(style)
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(border)).Render(rule)
+}
+
+// injectFootnotes replaces the footnotes placeholder line in rendered with the
+// section rule.
+func injectFootnotes(rendered string, width int, style string) string {
+	lines := strings.Split(rendered, "\n")
+	for i, line := range lines {
+		if strings.Contains(stripANSI(line), footnotesPlaceholder) {
+			lines[i] = renderFootnotesRule(width, style)
+			break
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// footnoteDefLines maps footnote numbers to the rendered line holding their
+// definition, given ANSI-stripped rendered lines.
+func footnoteDefLines(lines []string) map[int]int {
+	defs := map[int]int{}
+	start := -1
+	for i, l := range lines {
+		if strings.HasPrefix(strings.TrimSpace(l), "── Footnotes ") {
+			start = i
+		}
+	}
+	if start < 0 {
+		return defs
+	}
+	for i := start + 1; i < len(lines); i++ {
+		if sub := footnoteItemRe.FindStringSubmatch(lines[i]); sub != nil {
+			n, _ := strconv.Atoi(sub[1])
+			defs[n] = i
+		}
+	}
+	return defs
+}
+
+// footnoteRef is a footnote reference as placed in a rendered document.
+type footnoteRef struct {
+	line int // rendered line the reference is on
+	n    int // footnote number
+}
+
+// takeFootnoteRefs records the line of each tagged footnote reference in
+// rendered and removes the tags.
+func takeFootnoteRefs(rendered string) (string, []footnoteRef) {
+	var refs []footnoteRef
+	lines := strings.Split(rendered, "\n")
+	for i, line := range lines {
+		if !strings.Contains(line, footnoteRefMark) {
+			continue
+		}
+		for _, sub := range footnoteRefMarkRe.FindAllStringSubmatch(stripANSI(line), -1) {
+			if n, ok := parseSuperscriptNumber(sub[1]); ok {
+				refs = append(refs, footnoteRef{line: i, n: n})
+			}
+		}
+		lines[i] = strings.ReplaceAll(line, footnoteRefMark, "")
+	}
+	return strings.Join(lines, "\n"), refs
+}
+
+// nextFootnoteRef returns the index in refs of the reference to follow from
+// lines [from, to) on screen: the first one after refs[last], wrapping around
+// to the first one on screen. References without a definition in defs are
+// skipped.
+func nextFootnoteRef(refs []footnoteRef, from, to, last int, defs map[int]int) (int, bool) {
+	first := -1
+	for i, r := range refs {
+		if r.line < from || r.line >= to {
+			continue
+		}
+		if _, ok := defs[r.n]; !ok {
+			continue
+		}
+		if i > last {
+			return i, true
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+	return first, first >= 0
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// extractFootnotes tests
+
+func TestExtractFootnotes_NumbersInReferenceOrder(t *testing.T) {
+	md := "One[^b] two[^a] again[^b].\n\n[^a]: Alpha\n[^b]: Beta\n"
+	prose, notes := extractFootnotes(md)
+	if len(notes) != 2 || notes[0].label != "b" || notes[1].label != "a" {
+		t.Fatalf("expected footnotes ordered b, a; got %+v", notes)
+	}
+	if !strings.Contains(prose, "One\u2063¹ two\u2063² again\u2063¹.") {
+		t.Errorf("expected superscript references, got %q", prose)
+	}
+	if !strings.Contains(prose, footnotesPlaceholder) || !strings.Contains(prose, "1. Beta ↩") {
+		t.Errorf("expected footnotes list after placeholder, got %q", prose)
+	}
+	if strings.Contains(prose, "[^a]:") {
+		t.Errorf("expected definitions removed, got %q", prose)
+	}
+}
+
+func TestExtractFootnotes_ContinuationLines(t *testing.T) {
+	_, notes := extractFootnotes("Ref[^x].\n\n[^x]: First line\n    second line\n")
+	if len(notes) != 1 || notes[0].text != "First line second line" {
+		t.Errorf("expected joined continuation, got %+v", notes)
+	}
+}
+
+func TestExtractFootnotes_LeavesUndefinedAndCodeSpans(t *testing.T) {
+	prose, _ := extractFootnotes("Missing[^nope] and `[^a]` but real[^a].\n\n[^a]: A\n")
+	if !strings.Contains(prose, "Missing[^nope]") {
+		t.Errorf("expected undefined reference left as written, got %q", prose)
+	}
+	if !strings.Contains(prose, "`[^a]`") || !strings.Contains(prose, "real\u2063¹") {
+		t.Errorf("expected code span untouched and real reference converted, got %q", prose)
+	}
+}
+
+func TestExtractFootnotes_NoDefinitions(t *testing.T) {
+	md := "Plain [^1] text\n"
+	if prose, notes := extractFootnotes(md); prose != md || notes != nil {
+		t.Errorf("expected unchanged input, got %q %+v", prose, notes)
+	}
+}
+
+func TestSuperscriptNumber_RoundTrip(t *testing.T) {
+	for _, n := range []int{1, 9, 10, 42} {
+		got, ok := parseSuperscriptNumber(superscriptNumber(n))
+		if !ok || got != n {
+			t.Errorf("round trip of %d gave %d, %v", n, got, ok)
+		}
+	}
+}
+
+// rendered line mapping tests
+
+func TestFootnoteDefLines(t *testing.T) {
+	lines := []string{"  Text¹ and²", "", "── Footnotes ────", "", "  1. One ↩", "  2. Two ↩"}
+	defs := footnoteDefLines(lines)
+	if defs[1] != 4 || defs[2] != 5 {
+		t.Fatalf("unexpected definition lines: %v", defs)
+	}
+}
+
+func TestTakeFootnoteRefs_IgnoresUntaggedSuperscripts(t *testing.T) {
+	rendered := "  x² is\n  a claim" + footnoteRefMark + "¹ and" + footnoteRefMark + "¹²"
+	out, refs := takeFootnoteRefs(rendered)
+	if strings.Contains(out, footnoteRefMark) {
+		t.Errorf("expected tags removed, got %q", out)
+	}
+	want := []footnoteRef{{line: 1, n: 1}, {line: 1, n: 12}}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("expected %v, got %v", want, refs)
+	}
+}
+
+func TestNextFootnoteRef_StepsThroughScreen(t *testing.T) {
+	refs := []footnoteRef{{line: 0, n: 1}, {line: 3, n: 2}, {line: 3, n: 3}, {line: 9, n: 4}}
+	defs := map[int]int{1: 20, 2: 21, 3: 22, 4: 23}
+	var got []int
+	last := -1
+	for range 4 {
+		i, ok := nextFootnoteRef(refs, 1, 8, last, defs)
+		if !ok {
+			t.Fatal("expected a reference on screen")
+		}
+		got = append(got, refs[i].n)
+		last = i
+	}
+	if want := []int{2, 3, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if _, ok := nextFootnoteRef(refs, 4, 8, -1, defs); ok {
+		t.Error("expected no reference outside the given range")
+	}
+}
+
+// End-to-end tests
+
+func TestRenderMarkdown_Footnotes_EndToEnd(t *testing.T) {
+	md := "# Title\n\nA claim[^src].\n\n[^src]: The source.\n"
+	out := stripANSI(renderMarkdown(md, "dark", 60))
+	for _, want := range []string{"claim¹", "── Footnotes ", "1. The source. ↩"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got %q", want, out)
+		}
+	}
+	if strings.Contains(out, footnotesPlaceholder) {
+		t.Error("expected placeholder replaced")
+	}
+}
+
+// model tests
+
+func TestModel_FootnoteJumpAndBack(t *testing.T) {
+	md := "Ref[^a].\n\n" + strings.Repeat("Filler paragraph.\n\n", 40) + "[^a]: Defined here.\n"
+	var tm tea.Model = newModel("doc.md", md, "notty")
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 60, Height: 12})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("F")})
+	m := tm.(model)
+	def := m.footnoteDefs[1]
+	if def == 0 || m.viewport.YOffset == 0 {
+		t.Fatalf("expected jump towards definition line %d, offset %d", def, m.viewport.YOffset)
+	}
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("F")})
+	if off := tm.(model).viewport.YOffset; off != 0 {
+		t.Errorf("expected jump back to top, got offset %d", off)
+	}
+}
+
+func TestModel_FootnoteSkipsMathAndSteps(t *testing.T) {
+	md := "Square $x^2$ first[^a] and second[^b].\n\n" + strings.Repeat("Filler paragraph.\n\n", 40) +
+		"[^a]: First note.\n[^b]: Second note.\n"
+	var tm tea.Model = newModel("doc.md", md, "notty")
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 60, Height: 12})
+	f := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("F")}
+	for _, n := range []int{1, 2, 1} {
+		tm, _ = tm.Update(f)
+		m := tm.(model)
+		if m.viewport.YOffset == 0 || m.footnoteRefs[m.footnoteLast].n != n {
+			t.Errorf("expected a jump to footnote %d, got footnote %d at offset %d",
+				n, m.footnoteRefs[m.footnoteLast].n, m.viewport.YOffset)
+		}
+		tm, _ = tm.Update(f)
+	}
+}
@@ -0,0 +1,518 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+var (
+	// atxRe matches an ATX heading, with or without the space after the
+	// hashes. Group 1 = hashes, group 2 = text.
+	atxRe = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?[ \t]*$`)
+
+	// closingHashesRe matches the optional closing sequence of an ATX heading.
+	closingHashesRe = regexp.MustCompile(`(?:^|[ \t]+)#+[ \t]*$`)
+
+	// setextRe matches a setext heading underline. Group 1 = "=" or "-" run.
+	setextRe = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+
+	// thematicBreakRe matches a thematic break made of *, - or _.
+	thematicBreakRe = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+
+	// bulletRe matches a bullet list item. Group 1 = indent, group 2 = marker,
+	// group 3 = rest of the line.
+	bulletRe = regexp.MustCompile(`^([ \t]*)([-*+])([ \t]+.*|)$`)
+
+	// orderedRe matches an ordered list item.
+	orderedRe = regexp.MustCompile(`^[ \t]*\d+[.)](?:[ \t]+|$)`)
+
+	// htmlBlockRe matches the line opening a raw HTML block: a tag, a
+	// comment, a declaration or a processing instruction.
+	htmlBlockRe = regexp.MustCompile(`^ {0,3}(?:</?[a-zA-Z][a-zA-Z0-9-]*(?:[ \t/>]|$)|<[!?])`)
+
+	// tableDelimRe matches the delimiter row of a pipe table.
+	tableDelimRe = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*$`)
+)
+
+// formatOptions carries formatter settings.
+type formatOptions struct {
+	wrap int // wrap paragraphs and list items at this width, 0 to leave lines as written
+}
+
+// formatMarkdown is formatDocument with default options.
+func formatMarkdown(src string) string {
+	return formatDocument(src, formatOptions{})
+}
+
+// formatDocument normalizes markdown source: ATX headings with one space and
+// no closing hashes, setext headings turned into ATX, "-" bullets, "**" and
+// "_" emphasis, "---" thematic breaks, backtick fences, aligned pipe tables,
+// blank lines around headings and fences, no runs of blank lines and no
+// trailing whitespace. Front matter, the contents of fenced code blocks and
+// raw HTML blocks are left alone.
+func formatDocument(src string, opts formatOptions) string {
+	body, _ := extractFrontMatter(src)
+	front := src[:len(src)-len(body)]
+
+	var out []string
+	// blank marks that a blank line is due before the next line, unless it
+	// ends the document.
+	blank := false
+	emit := func(line string) {
+		if blank && len(out) > 0 {
+			out = append(out, "")
+		}
+		blank = false
+		out = append(out, line)
+	}
+	lastLine := func() string {
+		if len(out) == 0 {
+			return ""
+		}
+		return out[len(out)-1]
+	}
+
+	lines := strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+	inList := false
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if sub := fenceRe.FindStringSubmatch(line); sub != nil {
+			char := sub[1][:1]
+			run := len(trimmed) - len(strings.TrimLeft(trimmed, char))
+			info := strings.TrimSpace(trimmed[run:])
+			end := closingFence(lines, i, char, run)
+			indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			code := lines[i+1 : end]
+			if char == "~" && !containsFence(code, "```") {
+				char = "`"
+			}
+			fence := strings.Repeat(char, run)
+			if indent == "" {
+				blank = len(out) > 0
+			}
+			emit(indent + fence + info)
+			out = append(out, code...)
+			if end < len(lines) {
+				out = append(out, indent+fence)
+			}
+			i = end
+			blank = indent == ""
+			continue
+		}
+
+		if trimmed == "" {
+			if len(out) > 0 && lastLine() != "" {
+				blank = true
+			}
+			continue
+		}
+
+		// A raw HTML block runs to the next blank line.
+		if htmlBlockRe.MatchString(line) {
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				emit(strings.TrimRight(lines[i], " \t"))
+			}
+			i--
+			continue
+		}
+
+		// Setext heading: a single paragraph line over an underline.
+		if i+1 < len(lines) && setextRe.MatchString(lines[i+1]) && !inList && isParagraphLine(line) &&
+			(i == 0 || strings.TrimSpace(lines[i-1]) == "") {
+			level := "#"
+			if strings.HasPrefix(strings.TrimSpace(lines[i+1]), "-") {
+				level = "##"
+			}
+			blank = len(out) > 0
+			emit(level + " " + normalizeEmphasis(trimmed))
+			blank = true
+			i++
+			continue
+		}
+
+		if sub := atxRe.FindStringSubmatch(line); sub != nil {
+			text := closingHashesRe.ReplaceAllString(sub[2], "")
+			heading := sub[1]
+			if text = strings.TrimSpace(text); text != "" {
+				heading += " " + normalizeEmphasis(text)
+			}
+			blank = len(out) > 0
+			emit(heading)
+			blank = true
+			inList = false
+			continue
+		}
+
+		// A dash line under paragraph text is a setext underline, which is
+		// left as written when the paragraph spans several lines.
+		if thematicBreakRe.MatchString(line) && !(setextRe.MatchString(line) && i > 0 && isParagraphLine(lines[i-1])) {
+			blank = len(out) > 0
+			emit("---")
+			blank = true
+			inList = false
+			continue
+		}
+
+		if isTableStart(lines, i) {
+			end := i + 2
+			for end < len(lines) && strings.Contains(lines[end], "|") && strings.TrimSpace(lines[end]) != "" {
+				end++
+			}
+			for _, row := range formatTable(lines[i:end]) {
+				emit(row)
+			}
+			i = end - 1
+			continue
+		}
+
+		item := false
+		if sub := bulletRe.FindStringSubmatch(line); sub != nil && (len(sub[1]) < 4 || inList) {
+			line = sub[1] + "-" + sub[3]
+			inList, item = true, true
+		} else if orderedRe.MatchString(line) {
+			inList, item = true, true
+		} else if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") && blank {
+			inList = false
+		}
+
+		if opts.wrap > 0 && !inList && isParagraphLine(line) {
+			// Reflow the whole paragraph.
+			end := i
+			for end < len(lines) && isParagraphLine(lines[end]) && !isTableStart(lines, end) &&
+				!(end > i && (fenceRe.MatchString(lines[end]) || thematicBreakRe.MatchString(lines[end]))) &&
+				!(end+1 < len(lines) && setextRe.MatchString(lines[end+1])) {
+				end++
+			}
+			if end > i {
+				for _, l := range reflowParagraph(lines[i:end], opts.wrap) {
+					emit(l)
+				}
+				i = end - 1
+				continue
+			}
+		}
+
+		line = formatLine(line, i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "")
+		if opts.wrap > 0 && item && ansi.StringWidth(line) > opts.wrap {
+			loc := listMarkerRe.FindStringIndex(line)
+			hang := strings.Repeat(" ", loc[1])
+			for j, l := range wrapWords(strings.Fields(line[loc[1]:]), opts.wrap-loc[1]) {
+				if j == 0 {
+					emit(line[:loc[1]] + l)
+				} else {
+					emit(hang + l)
+				}
+			}
+			continue
+		}
+		emit(line)
+	}
+	if len(out) == 0 {
+		return front
+	}
+	return front + strings.Join(out, "\n") + "\n"
+}
+
+// listMarkerRe matches the marker of a list item with its indent and the
+// spaces after it.
+var listMarkerRe = regexp.MustCompile(`^[ \t]*(?:[-*+]|\d+[.)])[ \t]+(?:\[[ xX]\][ \t]+)?`)
+
+var (
+	// strongUnderscoreRe matches __strong__ text. Group 1 = text.
+	strongUnderscoreRe = regexp.MustCompile(`(^|[^\w_])__([^_\s](?:[^_]*[^_\s])?)__($|[^\w_])`)
+
+	// emphasisStarRe matches *emphasis* outside words. Group 2 = text.
+	emphasisStarRe = regexp.MustCompile(`(^|[^\w*\\])\*([^*\s](?:[^*]*[^*\s\\])?)\*($|[^\w*])`)
+
+	// literalSpanRe matches text emphasis markers cannot appear in: link
+	// destinations, reference definitions, autolinks, bare URLs, HTML tags
+	// and inline math.
+	literalSpanRe = regexp.MustCompile(`\]\(\s*(?:<[^<>]*>|[^)\s]*)|^ {0,3}\[[^\]]+\]:\s*\S+|` +
+		`<[a-zA-Z][a-zA-Z0-9+.-]*:[^\s<>]*>|\b[a-zA-Z][a-zA-Z0-9+.-]*://[^\s<>()]+|</?[a-zA-Z][^<>]*>|` +
+		`\$\$[^$]+\$\$|\$[^$\s](?:[^$]*[^$\s])?\$`)
+)
+
+// normalizeEmphasis rewrites __strong__ as **strong** and *emphasis* as
+// _emphasis_, outside code spans and literal text.
+func normalizeEmphasis(line string) string {
+	return mapOutsideLiterals(line, func(s string) string {
+		s = strongUnderscoreRe.ReplaceAllString(s, "$1**$2**$3")
+		return emphasisStarRe.ReplaceAllString(s, "${1}_${2}_${3}")
+	})
+}
+
+// mapOutsideLiterals applies fn to the parts of line outside code spans and
+// the literal text literalSpanRe matches.
+func mapOutsideLiterals(line string, fn func(string) string) string {
+	return mapOutsideCodeSpans(line, func(s string) string {
+		var b strings.Builder
+		last := 0
+		for _, loc := range literalSpanRe.FindAllStringIndex(s, -1) {
+			b.WriteString(fn(s[last:loc[0]]))
+			b.WriteString(s[loc[0]:loc[1]])
+			last = loc[1]
+		}
+		b.WriteString(fn(s[last:]))
+		return b.String()
+	})
+}
+
+// formatLine normalizes the emphasis and trailing whitespace of a line of
+// text. Two or more trailing spaces before more text are a hard line break,
+// kept as a backslash.
+func formatLine(line string, more bool) string {
+	if strings.HasPrefix(line, "    ") || strings.HasPrefix(strings.TrimSpace(line), "<") {
+		return strings.TrimRight(line, " \t")
+	}
+	hard := more && strings.HasSuffix(line, "  ")
+	line = normalizeEmphasis(strings.TrimRight(line, " \t"))
+	if hard {
+		line += "\\"
+	}
+	return line
+}
+
+// reflowParagraph joins the lines of a paragraph and wraps them at width.
+// Hard line breaks are kept.
+func reflowParagraph(lines []string, width int) []string {
+	var out, words []string
+	for i, l := range lines {
+		l = formatLine(l, i+1 < len(lines))
+		words = append(words, strings.Fields(l)...)
+		if strings.HasSuffix(l, "\\") {
+			out = append(out, wrapWords(words, width)...)
+			words = nil
+		}
+	}
+	if len(words) > 0 {
+		out = append(out, wrapWords(words, width)...)
+	}
+	return out
+}
+
+// blockStartRe matches words that would start a different block if they
+// began a line.
+var blockStartRe = regexp.MustCompile(`^(?:[-*+>:]|#{1,6}|\d+[.)]|=+|-+|\|.*)$`)
+
+// wrapWords fills lines of at most width cells with words. Long words get a
+// line of their own, and no line starts with a word that would turn it into
+// another block.
+func wrapWords(words []string, width int) []string {
+	var lines []string
+	cur := ""
+	for _, w := range words {
+		switch {
+		case cur == "":
+			cur = w
+		case ansi.StringWidth(cur)+1+ansi.StringWidth(w) <= width || blockStartRe.MatchString(w):
+			cur += " " + w
+		default:
+			lines = append(lines, cur)
+			cur = w
+		}
+	}
+	if cur != "" {
+		lines = append(lines, cur)
+	}
+	return lines
+}
+
+// closingFence returns the index of the line closing the fence of run char
+// characters opened on line start, or len(lines) when the block runs to the
+// end.
+func closingFence(lines []string, start int, char string, run int) int {
+	for j := start + 1; j < len(lines); j++ {
+		t := strings.TrimSpace(lines[j])
+		rest := strings.TrimLeft(t, char)
+		if len(t)-len(rest) >= run && rest == "" {
+			return j
+		}
+	}
+	return len(lines)
+}
+
+// containsFence reports whether any of lines opens or closes a fence.
+func containsFence(lines []string, fence string) bool {
+	for _, l := range lines {
+		if strings.HasPrefix(strings.TrimSpace(l), fence) {
+			return true
+		}
+	}
+	return false
+}
+
+// isParagraphLine reports whether line could be paragraph text rather than
+// the start of another block, a definition (": text") or a link reference
+// definition.
+func isParagraphLine(line string) bool {
+	t := strings.TrimSpace(line)
+	return t != "" && !strings.HasPrefix(line, "    ") && !strings.HasPrefix(t, ">") &&
+		!strings.HasPrefix(t, "<") && !strings.HasPrefix(t, "|") && !strings.HasPrefix(t, ": ") &&
+		!linkDefRe.MatchString(line) &&
+		!bulletRe.MatchString(line) && !orderedRe.MatchString(line) && !atxRe.MatchString(line)
+}
+
+// isTableStart reports whether a pipe table starts at line i: a header row
+// followed by a delimiter row with as many cells.
+func isTableStart(lines []string, i int) bool {
+	if i+1 >= len(lines) || !strings.Contains(lines[i], "|") || !tableDelimRe.MatchString(lines[i+1]) {
+		return false
+	}
+	return len(splitTableRow(lines[i])) == len(splitTableRow(lines[i+1]))
+}
+
+// splitTableRow returns the trimmed cells of a pipe table row.
+func splitTableRow(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`) {
+		row = row[:len(row)-1]
+	}
+	var cells []string
+	var cell strings.Builder
+	inCode := false
+	for i := 0; i < len(row); i++ {
+		c := row[i]
+		switch {
+		case c == '\\' && i+1 < len(row):
+			cell.WriteByte(c)
+			cell.WriteByte(row[i+1])
+			i++
+			continue
+		case c == '`':
+			inCode = !inCode
+		case c == '|' && !inCode:
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+			continue
+		}
+		cell.WriteByte(c)
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// formatTable aligns the columns of a pipe table, keeping each column's
+// alignment markers.
+func formatTable(rows []string) []string {
+	cells := make([][]string, len(rows))
+	cols := 0
+	for i, r := range rows {
+		cells[i] = splitTableRow(r)
+		cols = max(cols, len(cells[i]))
+	}
+	type align struct{ left, right bool }
+	aligns := make([]align, cols)
+	for j, d := range cells[1] {
+		aligns[j] = align{strings.HasPrefix(d, ":"), strings.HasSuffix(d, ":")}
+	}
+	widths := make([]int, cols)
+	for i, row := range cells {
+		if i == 1 {
+			continue
+		}
+		for j, c := range row {
+			widths[j] = max(widths[j], ansi.StringWidth(c))
+		}
+	}
+	for j := range widths {
+		widths[j] = max(widths[j], 3)
+	}
+
+	out := make([]string, len(rows))
+	for i, row := range cells {
+		parts := make([]string, cols)
+		for j := 0; j < cols; j++ {
+			if i == 1 {
+				dashes := widths[j]
+				a := aligns[j]
+				d := strings.Repeat("-", dashes)
+				if a.left {
+					d = ":" + d[1:]
+				}
+				if a.right {
+					d = d[:len(d)-1] + ":"
+				}
+				parts[j] = d
+				continue
+			}
+			c := ""
+			if j < len(row) {
+				c = row[j]
+			}
+			pad := widths[j] - ansi.StringWidth(c)
+			switch a := aligns[j]; {
+			case a.right && !a.left:
+				parts[j] = strings.Repeat(" ", pad) + c
+			case a.right && a.left:
+				parts[j] = strings.Repeat(" ", pad/2) + c + strings.Repeat(" ", pad-pad/2)
+			default:
+				parts[j] = c + strings.Repeat(" ", pad)
+			}
+		}
+		out[i] = "| " + strings.Join(parts, " | ") + " |"
+	}
+	return out
+}
+
+// runFmt implements "incipit fmt": it prints each file formatted, rewrites it
+// with --write, or with --check lists the files that are not formatted and
+// fails. It returns the exit status.
+func runFmt(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	write := fs.Bool("write", false, "rewrite files in place")
+	check := fs.Bool("check", false, "exit non-zero if any file is not formatted")
+	wrap := fs.Int("wrap", 0, "wrap paragraphs and list items at this width (0 leaves lines as written)")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: incipit fmt [--write|--check] [--wrap N] <file.md>...\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || *write && *check || *wrap < 0 {
+		fs.Usage()
+		return 2
+	}
+
+	opts := formatOptions{wrap: *wrap}
+	status := 0
+	for _, filename := range fs.Args() {
+		info, err := os.Stat(filename)
+		var data []byte
+		if err == nil {
+			data, err = os.ReadFile(filename)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "incipit: %s\n", err)
+			status = 1
+			continue
+		}
+		formatted := formatDocument(string(data), opts)
+		switch {
+		case *check:
+			if formatted != string(data) {
+				fmt.Fprintf(stderr, "%s: not formatted\n", filename)
+				status = 1
+			}
+		case *write:
+			if formatted == string(data) {
+				continue
+			}
+			if err := writeFileAtomic(filename, []byte(formatted), info.Mode().Perm()); err != nil {
+				fmt.Fprintf(stderr, "incipit: %s: %s\n", filename, err)
+				status = 1
+			}
+		default:
+			fmt.Fprint(stdout, formatted)
+		}
+	}
+	return status
+}
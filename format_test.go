@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatMarkdown_Headings(t *testing.T) {
+	got := formatMarkdown("Title\n=====\nIntro.\n##   Usage ##\nText.\n\nSub\n---\n")
+	want := "# Title\n\nIntro.\n\n## Usage\n\nText.\n\n## Sub\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatMarkdown_ListsAndBreaks(t *testing.T) {
+	got := formatMarkdown("* one\n+ two\n    * nested\n\n\n\n***\nEnd  \nline\n")
+	want := "- one\n- two\n    - nested\n\n---\n\nEnd\\\nline\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatMarkdown_Fences(t *testing.T) {
+	got := formatMarkdown("Text.\n~~~python\nx = 1   \n\n\n* not a list\n~~~\nAfter.\n")
+	want := "Text.\n\n```python\nx = 1   \n\n\n* not a list\n```\n\nAfter.\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// Tildes stay when the code holds backtick fences.
+	in := "~~~~md\n```go\n```\n~~~~\n"
+	if got := formatMarkdown(in); got != in {
+		t.Errorf("expected tilde fence kept, got %q", got)
+	}
+}
+
+func TestFormatMarkdown_Table(t *testing.T) {
+	got := formatMarkdown("|a|b|c|\n|:-|--:|:-:|\n|long cell|x|`a|b`|\n")
+	want := "| a         |   b |   c   |\n| :-------- | --: | :---: |\n| long cell |   x | `a|b` |\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatMarkdown_KeepsFrontMatterAndIsIdempotent(t *testing.T) {
+	in := "---\ntitle:  Spaced  \n---\n# Doc\n\n\nText.   \n"
+	got := formatMarkdown(in)
+	if !strings.HasPrefix(got, "---\ntitle:  Spaced  \n---\n# Doc\n\nText.\n") {
+		t.Errorf("unexpected output %q", got)
+	}
+	if again := formatMarkdown(got); again != got {
+		t.Errorf("expected formatting to be idempotent, got %q then %q", got, again)
+	}
+}
+
+func TestFormatMarkdown_MultilineSetextLeftAlone(t *testing.T) {
+	in := "Two line\nheading\n---\n"
+	if got := formatMarkdown(in); got != in {
+		t.Errorf("expected a multi-line setext heading kept, got %q", got)
+	}
+}
+
+func TestFormatMarkdown_Emphasis(t *testing.T) {
+	got := formatMarkdown("A __strong__ and *em* word, `*code*`, snake_case and 2*3*4.\n")
+	want := "A **strong** and _em_ word, `*code*`, snake_case and 2*3*4.\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatMarkdown_EmphasisLeavesLiterals(t *testing.T) {
+	in := "See [init](src/__init__.py), <http://x/*a*/b*>, https://x.y/__z__ and <span class=\"*a*\">.\n" +
+		"Math $a*b*c$ and __init__.\n\n[ref]: lib/__init__.py\n"
+	want := "See [init](src/__init__.py), <http://x/*a*/b*>, https://x.y/__z__ and <span class=\"*a*\">.\n" +
+		"Math $a*b*c$ and **init**.\n\n[ref]: lib/__init__.py\n"
+	if got := formatMarkdown(in); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatMarkdown_LeavesHTMLBlocks(t *testing.T) {
+	in := "<div>\n  raw *html*\n  - not a list\n</div>\n\nAfter *that*.\n"
+	want := "<div>\n  raw *html*\n  - not a list\n</div>\n\nAfter _that_.\n"
+	if got := formatMarkdown(in); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatDocument_WrapAfterList(t *testing.T) {
+	in := "- a\n- b\n\nOne two three four five six seven.\n"
+	want := "- a\n- b\n\nOne two three four\nfive six seven.\n"
+	if got := formatDocument(in, formatOptions{wrap: 20}); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatDocument_WrapKeepsDefinitions(t *testing.T) {
+	in := "Term\n: The definition.\n: Another one.\n\n[a]: https://a.example\n[b]: https://b.example\n"
+	if got := formatDocument(in, formatOptions{wrap: 40}); got != in {
+		t.Errorf("expected definitions kept on their own lines, got %q", got)
+	}
+}
+
+func TestFormatDocument_Wrap(t *testing.T) {
+	in := "One two three four five six seven - eight nine ten.\nEleven.  \nTwelve.\n\n* item one two three four five six seven\n"
+	got := formatDocument(in, formatOptions{wrap: 20})
+	want := "One two three four\nfive six seven -\neight nine ten.\nEleven.\\\nTwelve.\n\n- item one two three\n  four five six\n  seven\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if again := formatDocument(got, formatOptions{wrap: 20}); again != got {
+		t.Errorf("expected wrapping to be idempotent, got %q", again)
+	}
+}
+
+func TestRunFmt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.md")
+	if err := os.WriteFile(path, []byte("Title\n=====\n* item\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	if code := runFmt([]string{"--check", path}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "not formatted") {
+		t.Errorf("expected --check to fail, got %d %q", code, stderr.String())
+	}
+	if code := runFmt([]string{"--write", path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("--write failed: %q", stderr.String())
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "# Title\n\n- item\n" {
+		t.Errorf("unexpected rewrite %q", data)
+	}
+	stderr.Reset()
+	if code := runFmt([]string{"--check", path}, &stdout, &stderr); code != 0 {
+		t.Errorf("expected --check to pass, got %d %q", code, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("expected no stdout, got %q", stdout.String())
+	}
+}
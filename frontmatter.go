@@ -0,0 +1,221 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+type metaField struct {
+	key   string
+	value string
+}
+
+// frontMatter holds the flattened key/value pairs of a YAML (---) or TOML (+++)
+// front matter block, in source order.
+type frontMatter struct {
+	format string // "yaml" or "toml"
+	fields []metaField
+}
+
+// get returns the value for key, or "" when the key is absent.
+func (fm *frontMatter) get(key string) string {
+	if fm == nil {
+		return ""
+	}
+	for _, f := range fm.fields {
+		if strings.EqualFold(f.key, key) {
+			return f.value
+		}
+	}
+	return ""
+}
+
+// extractFrontMatter strips a leading front matter block from md and returns
+// the remaining document plus the parsed block. YAML blocks are delimited by
+// "---" (closed by "---" or "..."), TOML blocks by "+++". When md has no front
+// matter it is returned unchanged with a nil *frontMatter.
+func extractFrontMatter(md string) (string, *frontMatter) {
+	text := strings.TrimPrefix(md, "\ufeff")
+	lines := strings.Split(text, "\n")
+	if len(lines) < 2 {
+		return md, nil
+	}
+
+	var format string
+	switch strings.TrimRight(lines[0], " \t\r") {
+	case "---":
+		format = "yaml"
+	case "+++":
+		format = "toml"
+	default:
+		return md, nil
+	}
+
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		l := strings.TrimRight(lines[i], " \t\r")
+		if (format == "yaml" && (l == "---" || l == "...")) || (format == "toml" && l == "+++") {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return md, nil
+	}
+
+	fm := &frontMatter{format: format}
+	if format == "yaml" {
+		fm.fields = parseYAMLFields(lines[1:end])
+	} else {
+		fm.fields = parseTOMLFields(lines[1:end])
+	}
+
+	rest := strings.Join(lines[end+1:], "\n")
+	return strings.TrimLeft(rest, "\r\n"), fm
+}
+
+// parseYAMLFields flattens the common subset of YAML used in front matter:
+// scalars, flow lists ([a, b]), block lists (- a), block scalars (| and >)
+// and one level of nested maps (reported as "parent.child").
+func parseYAMLFields(lines []string) []metaField {
+	var fields []metaField
+	parent := ""
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indented := line != trimmed && strings.HasPrefix(line, " ")
+		if !indented {
+			parent = ""
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok || strings.HasPrefix(trimmed, "- ") {
+			continue
+		}
+		key = unquote(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if indented && parent != "" {
+			key = parent + "." + key
+		}
+
+		switch {
+		case value == "":
+			// Either a block list or a nested map follows.
+			var items []string
+			for i+1 < len(lines) {
+				next := strings.TrimSpace(lines[i+1])
+				if !strings.HasPrefix(next, "- ") && next != "-" {
+					break
+				}
+				items = append(items, unquote(strings.TrimSpace(strings.TrimPrefix(next, "-"))))
+				i++
+			}
+			if len(items) > 0 {
+				fields = append(fields, metaField{key: key, value: strings.Join(items, ", ")})
+			} else if !indented {
+				parent = key
+			}
+		case value == "|" || value == ">" || value == "|-" || value == ">-":
+			var parts []string
+			for i+1 < len(lines) && (strings.HasPrefix(lines[i+1], " ") || strings.TrimSpace(lines[i+1]) == "") {
+				parts = append(parts, strings.TrimSpace(lines[i+1]))
+				i++
+			}
+			fields = append(fields, metaField{key: key, value: strings.TrimSpace(strings.Join(parts, " "))})
+		default:
+			fields = append(fields, metaField{key: key, value: flowValue(value)})
+		}
+	}
+	return fields
+}
+
+// parseTOMLFields flattens TOML key/value pairs. Keys inside a [table] are
+// reported as "table.key".
+func parseTOMLFields(lines []string) []metaField {
+	var fields []metaField
+	table := ""
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			table = strings.Trim(trimmed, "[] ")
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, "=")
+		if !ok {
+			continue
+		}
+		key = unquote(strings.TrimSpace(key))
+		if table != "" {
+			key = table + "." + key
+		}
+		fields = append(fields, metaField{key: key, value: flowValue(strings.TrimSpace(value))})
+	}
+	return fields
+}
+
+// flowValue turns an inline scalar or [a, b] list into display text.
+func flowValue(v string) string {
+	if strings.HasPrefix(v, "[") && strings.HasSuffix(v, "]") {
+		var items []string
+		for _, item := range strings.Split(v[1:len(v)-1], ",") {
+			if item = unquote(strings.TrimSpace(item)); item != "" {
+				items = append(items, item)
+			}
+		}
+		return strings.Join(items, ", ")
+	}
+	return unquote(v)
+}
+
+// unquote strips one pair of matching single or double quotes.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// renderFrontMatter renders front matter as a compact key/value card sized to
+// its content, capped at width. For "notty" style it carries no ANSI codes.
+func renderFrontMatter(fm *frontMatter, width int, style string) string {
+	if fm == nil || len(fm.fields) == 0 {
+		return ""
+	}
+
+	keyWidth := 0
+	for _, f := range fm.fields {
+		if w := lipgloss.Width(f.key); w > keyWidth {
+			keyWidth = w
+		}
+	}
+
+	var borderColor string
+	ks := lipgloss.NewStyle()
+	if style != "notty" {
+		fg, _, _ := headerColors(2, style)
+		ks = ks.Foreground(lipgloss.Color(fg)).Bold(true)
+		_, borderColor = codeBlockColors(style)
+	}
+
+	cardWidth := len(fm.format) + 6
+	var lines []string
+	for _, f := range fm.fields {
+		key := f.key + strings.Repeat(" ", keyWidth-lipgloss.Width(f.key))
+		line := ks.Render(key) + "  " + f.value
+		lines = append(lines, line)
+		if w := lipgloss.Width(line) + 4; w > cardWidth {
+			cardWidth = w
+		}
+	}
+	if cardWidth > width {
+		cardWidth = width
+	}
+	return renderFrame(fm.format, lines, cardWidth, borderColor)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// extractFrontMatter tests
+
+func TestExtractFrontMatter_YAML(t *testing.T) {
+	md := "---\ntitle: \"Design: v2\"\ntags: [go, cli]\nauthors:\n  - Ann\n  - Bob\n---\n\n# Heading\n"
+	body, fm := extractFrontMatter(md)
+	if fm == nil {
+		t.Fatal("expected front matter to be detected")
+	}
+	if fm.format != "yaml" {
+		t.Errorf("expected yaml format, got %q", fm.format)
+	}
+	if body != "# Heading\n" {
+		t.Errorf("expected front matter stripped from body, got %q", body)
+	}
+	if got := fm.get("title"); got != "Design: v2" {
+		t.Errorf("expected unquoted title, got %q", got)
+	}
+	if got := fm.get("tags"); got != "go, cli" {
+		t.Errorf("expected flow list joined, got %q", got)
+	}
+	if got := fm.get("authors"); got != "Ann, Bob" {
+		t.Errorf("expected block list joined, got %q", got)
+	}
+}
+
+func TestExtractFrontMatter_YAMLNestedMap(t *testing.T) {
+	_, fm := extractFrontMatter("---\nparams:\n  draft: true\n---\n")
+	if got := fm.get("params.draft"); got != "true" {
+		t.Errorf("expected nested key flattened, got %q", got)
+	}
+}
+
+func TestExtractFrontMatter_TOML(t *testing.T) {
+	md := "+++\ntitle = 'Release notes'\n[extra]\nweight = 10\n+++\nBody text."
+	body, fm := extractFrontMatter(md)
+	if fm == nil {
+		t.Fatal("expected front matter to be detected")
+	}
+	if fm.format != "toml" {
+		t.Errorf("expected toml format, got %q", fm.format)
+	}
+	if fm.get("title") != "Release notes" {
+		t.Errorf("expected title, got %q", fm.get("title"))
+	}
+	if fm.get("extra.weight") != "10" {
+		t.Errorf("expected table key flattened, got %q", fm.get("extra.weight"))
+	}
+	if body != "Body text." {
+		t.Errorf("unexpected body: %q", body)
+	}
+}
+
+func TestExtractFrontMatter_None(t *testing.T) {
+	md := "# Title\n\n---\n\nAfter a rule.\n"
+	body, fm := extractFrontMatter(md)
+	if fm != nil {
+		t.Errorf("expected no front matter, got %+v", fm)
+	}
+	if body != md {
+		t.Errorf("expected body unchanged, got %q", body)
+	}
+}
+
+func TestExtractFrontMatter_Unterminated(t *testing.T) {
+	md := "---\ntitle: x\n\nNo closing delimiter."
+	body, fm := extractFrontMatter(md)
+	if fm != nil {
+		t.Error("expected unterminated block not to be treated as front matter")
+	}
+	if body != md {
+		t.Errorf("expected body unchanged, got %q", body)
+	}
+}
+
+// renderFrontMatter tests
+
+func TestRenderFrontMatter_Card(t *testing.T) {
+	fm := &frontMatter{format: "yaml", fields: []metaField{{"title", "Doc"}, {"author", "Ann"}}}
+	out := stripANSI(renderFrontMatter(fm, 80, "dark"))
+	lines := strings.Split(out, "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines (border, 2 fields, border), got %d: %q", len(lines), out)
+	}
+	if !strings.HasPrefix(lines[0], "╭── yaml ") {
+		t.Errorf("expected format label in top border, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "title   Doc") {
+		t.Errorf("expected aligned key/value, got %q", lines[1])
+	}
+	if w := len([]rune(lines[0])); w >= 80 {
+		t.Errorf("expected compact card narrower than the page, got width %d", w)
+	}
+}
+
+func TestRenderFrontMatter_NottyNoANSI(t *testing.T) {
+	fm := &frontMatter{format: "toml", fields: []metaField{{"title", "Doc"}}}
+	out := renderFrontMatter(fm, 80, "notty")
+	if out != stripANSI(out) {
+		t.Error("expected no ANSI codes in notty card")
+	}
+}
+
+func TestRenderFrontMatter_Empty(t *testing.T) {
+	if out := renderFrontMatter(nil, 80, "dark"); out != "" {
+		t.Errorf("expected empty output for nil front matter, got %q", out)
+	}
+}
+
+// model tests
+
+func TestModel_TitleFromFrontMatter(t *testing.T) {
+	m := newModel("doc.md", "---\ntitle: Handbook\n---\n# Hi\n", "dark")
+	if m.title() != "Handbook" {
+		t.Errorf("expected front matter title, got %q", m.title())
+	}
+	if strings.Contains(m.rawMarkdown, "title:") {
+		t.Error("expected front matter stripped from rawMarkdown")
+	}
+}
+
+func TestModel_TitleFallsBackToFilename(t *testing.T) {
+	m := newModel("doc.md", "# Hi\n", "dark")
+	if m.title() != "doc.md" {
+		t.Errorf("expected filename, got %q", m.title())
+	}
+}
+
+func TestModel_FooterShowsMetaKey(t *testing.T) {
+	var tm tea.Model = newModel("doc.md", "---\ntitle: Handbook\n---\n# Hi\n", "notty")
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	if !strings.Contains(tm.View(), "m meta") {
+		t.Error("expected the footer to list the m key when there is front matter")
+	}
+	tm = newModel("doc.md", "# Hi\n", "notty")
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	if strings.Contains(tm.View(), "m meta") {
+		t.Error("expected no m key without front matter")
+	}
+}
+
+func TestModel_FooterListsDocumentKeys(t *testing.T) {
+	md := "# Hi\n\n- [ ] todo\n\nNote[^1].\n\n<details>\n<summary>More</summary>\n\nBody.\n</details>\n\n[^1]: Text.\n"
+	var tm tea.Model = newModel("doc.md", md, "notty")
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 20})
+	for _, hint := range []string{"z fold", "o open", "F notes", "t tasks", "w width", "S all docs", "q quit"} {
+		if !strings.Contains(tm.View(), hint) {
+			t.Errorf("expected the footer to list %q, got %q", hint, tm.View())
+		}
+	}
+	tm = newModel("doc.md", "Plain text.\n", "notty")
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 20})
+	for _, hint := range []string{"z fold", "o open", "F notes", "t tasks"} {
+		if strings.Contains(tm.View(), hint) {
+			t.Errorf("expected no %q for a plain document", hint)
+		}
+	}
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 40, Height: 20})
+	lines := strings.Split(tm.View(), "\n")
+	if footer := lines[len(lines)-1]; !strings.Contains(footer, "q quit") || lipgloss.Width(footer) > 40 {
+		t.Errorf("expected a narrow footer to keep q quit on one line, got %q", footer)
+	}
+}
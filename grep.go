@@ -0,0 +1,588 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// grepMatch is a line of a document matching a search.
+type grepMatch struct {
+	line    int      // 1-based line in the file
+	text    string   // the matching line
+	spans   [][]int  // byte ranges of the matches in text
+	section []string // texts of the headings enclosing the line, outermost first
+	before  []string // context lines before the match
+	after   []string // context lines after the match
+}
+
+// grepResult holds the matches of one file.
+type grepResult struct {
+	file    string
+	matches []grepMatch
+	err     error
+}
+
+// grepFile searches a file's contents line by line, keeping up to
+// contextLines lines around each match.
+func grepFile(file, content string, re *regexp.Regexp, contextLines int) []grepMatch {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	headings := documentHeadings(content)
+	var matches []grepMatch
+	h := -1
+	for i, line := range lines {
+		for h+1 < len(headings) && headings[h+1].line <= i {
+			h++
+		}
+		spans := re.FindAllStringIndex(line, -1)
+		if spans == nil {
+			continue
+		}
+		m := grepMatch{line: i + 1, text: line, spans: spans}
+		if h >= 0 {
+			m.section = headings[h].path
+		}
+		m.before = lines[max(0, i-contextLines):i]
+		m.after = lines[i+1 : min(len(lines), i+1+contextLines)]
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// searchFiles searches the markdown files under root concurrently and sends
+// the result of each file with matches or errors as soon as it is done. The
+// channel is closed when every file has been searched or ctx is cancelled.
+func searchFiles(ctx context.Context, root string, re *regexp.Regexp, contextLines int) <-chan grepResult {
+	const workers = 8
+	results := make(chan grepResult)
+	go func() {
+		defer close(results)
+		files, err := markdownFiles(root)
+		if err != nil {
+			select {
+			case results <- grepResult{file: root, err: err}:
+			case <-ctx.Done():
+			}
+			return
+		}
+		queue := make(chan string)
+		var wg sync.WaitGroup
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for file := range queue {
+					data, err := os.ReadFile(file)
+					r := grepResult{file: file, err: err}
+					if err == nil {
+						r.matches = grepFile(file, string(data), re, contextLines)
+					}
+					if r.err == nil && len(r.matches) == 0 {
+						continue
+					}
+					select {
+					case results <- r:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+	feed:
+		for _, file := range files {
+			select {
+			case queue <- file:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(queue)
+		wg.Wait()
+	}()
+	return results
+}
+
+// compilePattern turns a search pattern into a regexp: a regular expression,
+// or a literal string when fixed is set.
+func compilePattern(pattern string, fixed, ignoreCase bool) (*regexp.Regexp, error) {
+	if fixed {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// Styles of search results.
+var (
+	grepFileStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("99"))
+	grepSectionStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	grepContextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	grepMatchStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("214"))
+)
+
+// highlightSpans renders the matched ranges of text in the match style.
+func highlightSpans(text string, spans [][]int) string {
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		b.WriteString(text[last:s[0]])
+		b.WriteString(grepMatchStyle.Render(text[s[0]:s[1]]))
+		last = s[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// formatGrepResult renders the matches of a file: the file name, then each
+// match under its section path, with context lines. Non-adjacent groups of
+// lines are separated by "--" as in grep.
+func formatGrepResult(r grepResult) string {
+	var b strings.Builder
+	b.WriteString(grepFileStyle.Render(r.file) + "\n")
+	printContext := func(n int, l string) {
+		fmt.Fprintf(&b, "  %s\n", grepContextStyle.Render(fmt.Sprintf("%d-%s", n, l)))
+	}
+	section := "\x00"
+	printed := 0 // last line printed
+	for _, m := range r.matches {
+		first := m.line - len(m.before)
+		if s := strings.Join(m.section, " › "); s != section {
+			section = s
+			if s != "" {
+				b.WriteString("  " + grepSectionStyle.Render(s) + "\n")
+			}
+		} else if printed > 0 && first > printed+1 {
+			b.WriteString("  --\n")
+		}
+		for i, l := range m.before {
+			if n := first + i; n > printed {
+				printContext(n, l)
+			}
+		}
+		fmt.Fprintf(&b, "  %d:%s\n", m.line, highlightSpans(m.text, m.spans))
+		printed = m.line
+		for i, l := range m.after {
+			n := m.line + 1 + i
+			if isMatchLine(r.matches, n) {
+				break // printed as a match
+			}
+			printContext(n, l)
+			printed = n
+		}
+	}
+	return b.String()
+}
+
+// isMatchLine reports whether line is one of the matches.
+func isMatchLine(matches []grepMatch, line int) bool {
+	for _, m := range matches {
+		if m.line == line {
+			return true
+		}
+	}
+	return false
+}
+
+// runGrep implements "incipit grep": it searches every markdown file under a
+// directory and prints the matches grouped by file and section, each file as
+// soon as it has been searched. Like grep, it returns 1 when nothing matches.
+func runGrep(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("grep", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	ignoreCase := fs.Bool("i", false, "ignore case")
+	fixed := fs.Bool("F", false, "match PATTERN as a literal string")
+	contextLines := fs.Int("C", 1, "lines of context around each match")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: incipit grep [-i] [-F] [-C N] PATTERN [dir]\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 || *contextLines < 0 {
+		fs.Usage()
+		return 2
+	}
+	re, err := compilePattern(fs.Arg(0), *fixed, *ignoreCase)
+	if err != nil {
+		fmt.Fprintf(stderr, "incipit: %s\n", err)
+		return 2
+	}
+	root := "."
+	if fs.NArg() == 2 {
+		root = fs.Arg(1)
+	}
+
+	status := 1
+	first := true
+	for r := range searchFiles(context.Background(), root, re, *contextLines) {
+		if r.err != nil {
+			fmt.Fprintf(stderr, "incipit: %s\n", r.err)
+			status = 2
+			continue
+		}
+		if !first {
+			fmt.Fprintln(stdout)
+		}
+		first = false
+		fmt.Fprint(stdout, formatGrepResult(r))
+		if status == 1 {
+			status = 0
+		}
+	}
+	return status
+}
+
+// grepResultMsg delivers the result of one file of a pager search.
+type grepResultMsg struct {
+	id      int
+	result  grepResult
+	results <-chan grepResult
+}
+
+// grepDoneMsg reports that a pager search has finished.
+type grepDoneMsg struct {
+	id int
+}
+
+// waitForResult returns a command reading the next result of a search.
+func waitForResult(id int, results <-chan grepResult) tea.Cmd {
+	return func() tea.Msg {
+		r, ok := <-results
+		if !ok {
+			return grepDoneMsg{id}
+		}
+		return grepResultMsg{id, r, results}
+	}
+}
+
+// docSearch is the pager's search across every document under a root.
+type docSearch struct {
+	id      int // increases with each search, to drop results of old ones
+	query   string
+	typing  bool // reading the query
+	active  bool // showing results
+	done    bool
+	results []grepResult // files with matches, in the order they arrived
+	cursor  int          // index of the selected match, counted across files
+	offset  int          // first result row on screen
+	err     string       // why the last match could not be opened
+	cancel  context.CancelFunc
+}
+
+// start cancels any running search and starts one for the query under root,
+// matching like the in-document search: a literal string, ignoring case.
+func (s *docSearch) start(root string) tea.Cmd {
+	s.stop()
+	s.id++
+	s.results = nil
+	s.cursor, s.offset = 0, 0
+	s.done = false
+	s.active = true
+	re, _ := compilePattern(s.query, true, true)
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	return waitForResult(s.id, searchFiles(ctx, root, re, 1))
+}
+
+// stop cancels a running search.
+func (s *docSearch) stop() {
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+}
+
+// hit returns the file and match of the nth match across files.
+func (s docSearch) hit(n int) (grepResult, int, bool) {
+	for _, r := range s.results {
+		if n < len(r.matches) {
+			return r, n, true
+		}
+		n -= len(r.matches)
+	}
+	return grepResult{}, 0, false
+}
+
+// hits returns the number of matches found so far.
+func (s docSearch) hits() int {
+	n := 0
+	for _, r := range s.results {
+		n += len(r.matches)
+	}
+	return n
+}
+
+// rows renders the results as screen rows of at most width cells, and
+// returns the row of each match.
+func (s docSearch) rows(root string, width int) ([]string, []int) {
+	var rows []string
+	var hitRows []int
+	add := func(row string) {
+		rows = append(rows, ansi.Truncate(row, width, "…"))
+	}
+	n := 0
+	for _, r := range s.results {
+		name := r.file
+		if rel, err := filepath.Rel(root, r.file); err == nil {
+			name = rel
+		}
+		if len(rows) > 0 {
+			add("")
+		}
+		if r.err != nil {
+			add(" " + grepFileStyle.Render(name) + grepContextStyle.Render("  "+r.err.Error()))
+			continue
+		}
+		add(" " + grepFileStyle.Render(name))
+		section := "\x00"
+		printed := 0 // last line shown
+		for _, m := range r.matches {
+			first := m.line - len(m.before)
+			if sec := strings.Join(m.section, " › "); sec != section {
+				section = sec
+				if sec != "" {
+					add("   " + grepSectionStyle.Render(sec))
+				}
+			} else if printed > 0 && first > printed+1 {
+				add("     " + grepContextStyle.Render("--"))
+			}
+			for i, l := range m.before {
+				if first+i > printed {
+					add("     " + grepContextStyle.Render(l))
+				}
+			}
+			row := fmt.Sprintf("%4d ", m.line) + highlightSpans(m.text, m.spans)
+			if n == s.cursor {
+				row = lipgloss.NewStyle().Reverse(true).Render(fmt.Sprintf("%4d ", m.line)) + highlightSpans(m.text, m.spans)
+			}
+			hitRows = append(hitRows, len(rows))
+			add(row)
+			printed = m.line
+			for i, l := range m.after {
+				if isMatchLine(r.matches, m.line+1+i) {
+					break // shown as a match
+				}
+				add("     " + grepContextStyle.Render(l))
+				printed = m.line + 1 + i
+			}
+			n++
+		}
+	}
+	return rows, hitRows
+}
+
+// openMatch shows the nth match of the search: it loads the match's file
+// when it is not the one being read, unfolds it and scrolls to the match,
+// leaving the query as the in-document search so n and N step through the
+// file.
+func (m *model) openMatch(n int) error {
+	r, i, ok := m.docSearch.hit(n)
+	if !ok {
+		return nil
+	}
+	if r.file != m.filename {
+		data, err := os.ReadFile(r.file)
+		if err != nil {
+			return err
+		}
+		m.load(r.file, string(data))
+	}
+	m.docSearch.active = false
+	m.searchQuery = m.docSearch.query
+	m.folded = map[int]bool{}
+	m.applyContent(m.contentWidth())
+	m.noMatches = len(m.matchLines) == 0
+	if len(m.matchLines) > 0 {
+		m.matchIdx = m.renderedMatch(r.matches, i)
+		m.viewport.GotoTop()
+		m.viewport.LineDown(m.matchLines[m.matchIdx])
+	}
+	return nil
+}
+
+// renderedMatch returns the index in matchLines of the line showing
+// matches[i], matches of the document being read. Matches are placed in
+// order, each on the first line after the previous one that is in its
+// section and shows its text. A match that is not shown, such as one in an
+// HTML comment, goes to the first line there instead.
+func (m model) renderedMatch(matches []grepMatch, i int) int {
+	row := map[int]int{} // content line → viewport line
+	for v, c := range m.visibleLines {
+		row[c] = v
+	}
+	// Pair source headings with rendered ones by text; headings inside
+	// callouts and other nested blocks are not rendered as headings.
+	type anchor struct{ line, row int } // 1-based file line and viewport line
+	var anchors []anchor
+	k := 0
+	for _, h := range scanHeadings(m.rawMarkdown) {
+		for j := k; j < len(m.headings); j++ {
+			if m.headings[j].text != h.text {
+				continue
+			}
+			if v, ok := row[m.headings[j].line]; ok {
+				anchors = append(anchors, anchor{m.bodyLine + h.line + 1, v})
+			}
+			k = j + 1
+			break
+		}
+	}
+	// section returns the viewport lines [from, to) of the section holding a
+	// file line.
+	section := func(line int) (from, to int) {
+		from, to = 0, len(m.visibleLines)
+		for _, a := range anchors {
+			if a.line > line {
+				return from, a.row
+			}
+			from = a.row
+		}
+		return from, to
+	}
+
+	next := 0 // first viewport line the next match can be on
+	for j, hit := range matches[:i+1] {
+		from, to := section(hit.line)
+		from = max(from, next)
+		first, shown := -1, -1
+		for x, line := range m.matchLines {
+			if line < from {
+				continue
+			}
+			if first < 0 {
+				first = x
+			}
+			if line < to && sharesText(m.searchLines[line], hit.text) {
+				shown = x
+				break
+			}
+		}
+		if j == i {
+			switch {
+			case shown >= 0:
+				return shown
+			case first >= 0:
+				return first
+			}
+			return len(m.matchLines) - 1
+		}
+		if shown >= 0 {
+			next = m.matchLines[shown] + 1
+		}
+	}
+	return 0
+}
+
+// sharesText reports whether a rendered line shows the text of a source line,
+// or part of it when the paragraph was wrapped, comparing letters and digits
+// only.
+func sharesText(rendered, source string) bool {
+	letters := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsNumber(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, s)
+	}
+	r, s := letters(rendered), letters(stripInlineMarkdown(source))
+	return r != "" && s != "" && (strings.Contains(r, s) || strings.Contains(s, r))
+}
+
+// updateDocSearch handles keys while the search across documents is read or
+// its results are shown.
+func (m model) updateDocSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s := &m.docSearch
+	if s.typing {
+		switch msg.Type {
+		case tea.KeyEnter:
+			s.typing = false
+			if s.query == "" {
+				return m, nil
+			}
+			return m, s.start(m.root)
+		case tea.KeyEsc:
+			s.typing = false
+		case tea.KeyBackspace:
+			if runes := []rune(s.query); len(runes) > 0 {
+				s.query = string(runes[:len(runes)-1])
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			s.query += string(msg.Runes)
+		}
+		return m, nil
+	}
+
+	height := m.viewport.Height
+	switch msg.String() {
+	case "q", "ctrl+c":
+		s.stop()
+		return m, tea.Quit
+	case "esc":
+		s.stop()
+		s.active = false
+	case "S":
+		s.typing = true
+	case "up", "k":
+		s.cursor = max(s.cursor-1, 0)
+	case "down", "j":
+		s.cursor = min(s.cursor+1, max(s.hits()-1, 0))
+	case "pgup", "b":
+		s.cursor = max(s.cursor-height/2, 0)
+	case "pgdown", "f", " ":
+		s.cursor = min(s.cursor+height/2, max(s.hits()-1, 0))
+	case "g":
+		s.cursor = 0
+	case "G":
+		s.cursor = max(s.hits()-1, 0)
+	case "enter":
+		if err := m.openMatch(s.cursor); err != nil {
+			s.err = err.Error()
+		}
+		return m, nil
+	}
+	// Keep the selected match on screen.
+	_, hitRows := s.rows(m.root, m.window)
+	if s.cursor < len(hitRows) {
+		row := hitRows[s.cursor]
+		if row < s.offset {
+			s.offset = max(row-1, 0)
+		}
+		if row >= s.offset+height {
+			s.offset = row - height + 2
+		}
+	}
+	return m, nil
+}
+
+// docSearchView renders the visible rows of the search results.
+func (m model) docSearchView() string {
+	rows, _ := m.docSearch.rows(m.root, m.window)
+	height := m.viewport.Height
+	if len(rows) == 0 {
+		msg := " searching…"
+		if m.docSearch.done {
+			msg = " no documents match"
+		}
+		rows = []string{grepContextStyle.Render(msg)}
+	}
+	start := min(m.docSearch.offset, max(len(rows)-1, 0))
+	rows = rows[start:min(len(rows), start+height)]
+	for len(rows) < height {
+		rows = append(rows, "")
+	}
+	return strings.Join(rows, "\n")
+}
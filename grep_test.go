@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestGrepFile(t *testing.T) {
+	md := "# Guide\n\nIntro.\n\n## Install\n\nRun make install.\nThen run it.\n"
+	matches := grepFile("guide.md", md, regexp.MustCompile(`(?i)run`), 1)
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %+v", matches)
+	}
+	m := matches[0]
+	if m.line != 7 || strings.Join(m.section, "/") != "Guide/Install" || m.spans[0][0] != 0 {
+		t.Errorf("unexpected match %+v", m)
+	}
+	if strings.Join(m.before, "|") != "" || strings.Join(m.after, "|") != "Then run it." {
+		t.Errorf("unexpected context %q %q", m.before, m.after)
+	}
+}
+
+func TestFormatGrepResult(t *testing.T) {
+	md := "# A\n\none\ntwo x\nthree x\nfour\nfive\nhalf\nseven x\n\n## B\n\nx\n"
+	r := grepResult{file: "doc.md", matches: grepFile("doc.md", md, regexp.MustCompile("x"), 1)}
+	want := "doc.md\n  A\n  3-one\n  4:two x\n  5:three x\n  6-four\n  --\n  8-half\n  9:seven x\n  10-\n  A › B\n  12-\n  13:x\n"
+	if got := stripANSI(formatGrepResult(r)); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSearchFiles(t *testing.T) {
+	dir := writeDocs(t, map[string]string{
+		"a.md":     "# A\n\nneedle\n",
+		"b/c.md":   "no match\n",
+		"b/d.md":   "Needle and needle\n",
+		"skip.txt": "needle\n",
+	})
+	var files []string
+	for r := range searchFiles(context.Background(), dir, regexp.MustCompile("needle"), 0) {
+		rel, _ := filepath.Rel(dir, r.file)
+		files = append(files, filepath.ToSlash(rel))
+	}
+	sort.Strings(files)
+	if strings.Join(files, ",") != "a.md,b/d.md" {
+		t.Errorf("unexpected files with matches %q", files)
+	}
+}
+
+func TestRunGrep(t *testing.T) {
+	dir := writeDocs(t, map[string]string{"doc.md": "# Doc\n\nHello World\n"})
+	var stdout, stderr bytes.Buffer
+	if code := runGrep([]string{"-i", "-C", "0", "world", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected status 0, got %d %q", code, stderr.String())
+	}
+	want := filepath.Join(dir, "doc.md") + "\n  Doc\n  3:Hello World\n"
+	if got := stripANSI(stdout.String()); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if code := runGrep([]string{"world", dir}, &stdout, &stderr); code != 1 {
+		t.Errorf("expected status 1 without -i, got %d", code)
+	}
+	if code := runGrep([]string{"(", dir}, &stdout, &stderr); code != 2 {
+		t.Errorf("expected status 2 for a bad pattern, got %d", code)
+	}
+}
+
+// runSearch feeds the messages of a pager search back into the model until
+// the search is done.
+func runSearch(t *testing.T, tm tea.Model, cmd tea.Cmd) tea.Model {
+	t.Helper()
+	for cmd != nil {
+		msg := cmd()
+		tm, cmd = tm.Update(msg)
+		if _, done := msg.(grepDoneMsg); done {
+			break
+		}
+	}
+	return tm
+}
+
+func TestModel_SearchAllDocs(t *testing.T) {
+	dir := writeDocs(t, map[string]string{
+		"index.md":      "# Index\n\nStart here.\n",
+		"docs/guide.md": "# Guide\n\n## Setup\n\nInstall the widget.\n\nUse the widget.\n",
+	})
+	m := newModel(filepath.Join(dir, "index.md"), "# Index\n\nStart here.\n", "notty")
+	m.root = dir
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+
+	tm = sendKeys(tm, "S", "w", "i", "d", "g", "e", "t")
+	tm, cmd := tm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	tm = runSearch(t, tm, cmd)
+	m = tm.(model)
+	if !m.docSearch.active || !m.docSearch.done || m.docSearch.hits() != 2 {
+		t.Fatalf("expected 2 matches, got %+v", m.docSearch)
+	}
+	view := stripANSI(m.View())
+	for _, want := range []string{"docs/guide.md", "Guide › Setup", "Install the widget.", "2 matches in 1 files"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected results to show %q, got:\n%s", want, view)
+		}
+	}
+
+	tm = sendKeys(tm, "j", "enter")
+	m = tm.(model)
+	if m.docSearch.active || m.filename != filepath.Join(dir, "docs", "guide.md") {
+		t.Fatalf("expected the guide to open, got %q", m.filename)
+	}
+	if len(m.matchLines) != 2 || m.matchIdx != 1 || m.searchQuery != "widget" {
+		t.Errorf("expected to land on the second match, got lines %v idx %d query %q", m.matchLines, m.matchIdx, m.searchQuery)
+	}
+}
+
+func TestDocSearch_RowsShowSharedContextOnce(t *testing.T) {
+	re, _ := compilePattern("widget", true, true)
+	doc := "a\nwidget one\nb\nwidget two\nc\n\nd\nwidget three\n"
+	s := docSearch{results: []grepResult{{file: "doc.md", matches: grepFile("doc.md", doc, re, 1)}}}
+	rows, hitRows := s.rows(".", 80)
+	var plain []string
+	for _, r := range rows {
+		plain = append(plain, strings.TrimSpace(stripANSI(r)))
+	}
+	want := []string{"doc.md", "a", "2 widget one", "b", "4 widget two", "c", "--", "d", "8 widget three"}
+	if strings.Join(plain, "|") != strings.Join(want, "|") {
+		t.Errorf("got rows %q, want %q", plain, want)
+	}
+	if len(hitRows) != 3 || hitRows[1] != 4 || hitRows[2] != 8 {
+		t.Errorf("unexpected match rows %v", hitRows)
+	}
+}
+
+func TestModel_OpenMatchSkipsHiddenHits(t *testing.T) {
+	doc := "# Guide\n\n<!-- widget note -->\n\nIntro.\n\nUse the widget.\n\n## More\n\nMore widget text.\n"
+	dir := writeDocs(t, map[string]string{"guide.md": doc})
+	path := filepath.Join(dir, "guide.md")
+	tm, _ := newModel(path, doc, "notty").Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m := tm.(model)
+	re, _ := compilePattern("widget", true, true)
+	m.docSearch.query = "widget"
+	m.docSearch.results = []grepResult{{file: path, matches: grepFile(path, doc, re, 1)}}
+	for n, want := range []string{"Use the widget.", "Use the widget.", "More widget text."} {
+		if err := m.openMatch(n); err != nil {
+			t.Fatal(err)
+		}
+		if got := m.searchLines[m.matchLines[m.matchIdx]]; !strings.Contains(got, want) {
+			t.Errorf("match %d: expected to land on %q, got %q", n, want, got)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// numberHeadings sets the hierarchical number (1, 1.2, 1.2.3) of each header.
+// A heading is numbered below the nearest shallower heading before it, so
+// skipped levels do not leave zeros in the numbers.
+func numberHeadings(headers []headerBlock) {
+	type entry struct{ level, count int }
+	var stack []entry
+	for i := range headers {
+		level := headers[i].level
+		popped := 0 // count of the last heading popped at the depth being filled
+		for len(stack) > 0 && stack[len(stack)-1].level > level {
+			popped = stack[len(stack)-1].count
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 && stack[len(stack)-1].level == level {
+			stack[len(stack)-1].count++
+		} else {
+			stack = append(stack, entry{level, popped + 1})
+		}
+		parts := make([]string, len(stack))
+		for j, e := range stack {
+			parts[j] = strconv.Itoa(e.count)
+		}
+		headers[i].number = strings.Join(parts, ".")
+	}
+}
+
+// headingSlug returns the GitHub anchor for a heading text: lowercased, with
+// punctuation dropped and spaces turned into hyphens.
+func headingSlug(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+// slugger hands out unique heading anchors the way GitHub does: the second
+// "Usage" becomes "usage-1", the third "usage-2".
+type slugger map[string]int
+
+func (s slugger) slug(text string) string {
+	base := headingSlug(text)
+	slug := base
+	for {
+		if _, seen := s[slug]; !seen {
+			break
+		}
+		s[base]++
+		slug = fmt.Sprintf("%s-%d", base, s[base])
+	}
+	s[slug] = 0
+	return slug
+}
+
+// anchorHeadings sets the unique GitHub anchor of each header.
+func anchorHeadings(headers []headerBlock) {
+	s := slugger{}
+	for i := range headers {
+		headers[i].anchor = s.slug(headingText(headers[i]))
+	}
+}
+
+// renderAnchor renders the anchor shown after a heading pill.
+func renderAnchor(anchor, style string) string {
+	if style == "notty" {
+		return "#" + anchor
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render("#" + anchor)
+}
+
+// sectionPath returns the texts of the headings enclosing rendered line,
+// outermost first.
+func sectionPath(headings []heading, line int) []string {
+	var stack []heading
+	for _, h := range headings {
+		if h.line > line {
+			break
+		}
+		for len(stack) > 0 && stack[len(stack)-1].level >= h.level {
+			stack = stack[:len(stack)-1]
+		}
+		stack = append(stack, h)
+	}
+	path := make([]string, len(stack))
+	for i, h := range stack {
+		path[i] = h.text
+	}
+	return path
+}
+
+// breadcrumb joins the title and section path with " › " into at most width
+// cells, dropping the outermost parts first and marking the cut with "…".
+// It reports whether the title is still shown.
+func breadcrumb(title string, path []string, width int) (string, bool) {
+	const sep = " › "
+	parts := append([]string{title}, path...)
+	crumb := strings.Join(parts, sep)
+	if ansi.StringWidth(crumb) <= width {
+		return crumb, true
+	}
+	for len(parts) > 1 {
+		parts = parts[1:]
+		crumb = "…" + sep + strings.Join(parts, sep)
+		if ansi.StringWidth(crumb) <= width {
+			return crumb, false
+		}
+	}
+	crumb = parts[0]
+	if width < 1 {
+		return "", false
+	}
+	return "…" + ansi.TruncateLeft(crumb, ansi.StringWidth(crumb)-width+1, ""), false
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestNumberHeadings(t *testing.T) {
+	headers := []headerBlock{{level: 1}, {level: 2}, {level: 3}, {level: 3}, {level: 2}, {level: 1}, {level: 2}}
+	numberHeadings(headers)
+	want := []string{"1", "1.1", "1.1.1", "1.1.2", "1.2", "2", "2.1"}
+	for i, h := range headers {
+		if h.number != want[i] {
+			t.Errorf("heading %d: got %q, want %q", i, h.number, want[i])
+		}
+	}
+}
+
+func TestNumberHeadings_SkippedLevels(t *testing.T) {
+	headers := []headerBlock{{level: 2}, {level: 4}, {level: 3}, {level: 2}}
+	numberHeadings(headers)
+	want := []string{"1", "1.1", "1.2", "2"}
+	for i, h := range headers {
+		if h.number != want[i] {
+			t.Errorf("heading %d: got %q, want %q", i, h.number, want[i])
+		}
+	}
+}
+
+func TestHeadingSlug(t *testing.T) {
+	cases := map[string]string{
+		"Installation":          "installation",
+		"From source (Linux)":   "from-source-linux",
+		"What's new in v1.2?":   "whats-new-in-v12",
+		"snake_case and-dashes": "snake_case-and-dashes",
+		"Café  au lait":         "café--au-lait",
+	}
+	for in, want := range cases {
+		if got := headingSlug(in); got != want {
+			t.Errorf("headingSlug(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSlugger_Deduplicates(t *testing.T) {
+	s := slugger{}
+	var got []string
+	for _, text := range []string{"Usage", "Usage", "Usage-1", "Usage"} {
+		got = append(got, s.slug(text))
+	}
+	want := []string{"usage", "usage-1", "usage-1-1", "usage-2"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("slug %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestHeadingSlug_FromMarkdown(t *testing.T) {
+	cases := map[string]string{
+		"`foo_bar` option":             "foo_bar-option",
+		"snake_case":                   "snake_case",
+		"Use [links](http://x.y) here": "use-links-here",
+		"**Bold** and _emphasis_":      "bold-and-emphasis",
+		"__init__ hooks":               "init-hooks",
+	}
+	for in, want := range cases {
+		if got := headingSlug(headingText(headerBlock{text: in})); got != want {
+			t.Errorf("slug of %q = %q, want %q", in, got, want)
+		}
+	}
+
+	md := "# `foo_bar` option\n\n## snake_case\n\n## Use [links](http://x.y) here\n\n## snake_case\n"
+	var slugs []string
+	for _, h := range scanHeadings(md) {
+		slugs = append(slugs, h.slug)
+	}
+	want := []string{"foo_bar-option", "snake_case", "use-links-here", "snake_case-1"}
+	if strings.Join(slugs, " ") != strings.Join(want, " ") {
+		t.Errorf("expected document slugs %v, got %v", want, slugs)
+	}
+	out := stripANSI(renderDocument(md, "notty", 60, renderOptions{showAnchors: true}))
+	for _, a := range want {
+		if !strings.Contains(out, "#"+a) {
+			t.Errorf("expected anchor #%s shown, got %q", a, out)
+		}
+	}
+}
+
+func TestRenderDocument_NumbersAndAnchors(t *testing.T) {
+	md := "# Guide\n\n## Usage\n\nText.\n\n## Usage\n\nMore.\n"
+	opts := renderOptions{numberHeadings: true, showAnchors: true}
+	out := stripANSI(renderDocument(md, "dark", 60, opts))
+	for _, want := range []string{"1 Guide", "#guide", "1.1 Usage", "#usage", "1.2 Usage", "#usage-1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got %q", want, out)
+		}
+	}
+
+	plain := stripANSI(renderMarkdown(md, "dark", 60))
+	if strings.Contains(plain, "1.1") || strings.Contains(plain, "#usage") {
+		t.Errorf("expected no numbers or anchors by default, got %q", plain)
+	}
+}
+
+func TestSectionPath(t *testing.T) {
+	headings := []heading{
+		{level: 1, text: "Guide", line: 1},
+		{level: 2, text: "Installation", line: 5},
+		{level: 3, text: "From source", line: 9},
+		{level: 2, text: "Usage", line: 15},
+	}
+	cases := map[int]string{
+		0:  "",
+		3:  "Guide",
+		10: "Guide › Installation › From source",
+		20: "Guide › Usage",
+	}
+	for line, want := range cases {
+		if got := strings.Join(sectionPath(headings, line), " › "); got != want {
+			t.Errorf("sectionPath(%d) = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestBreadcrumb_TruncatesFromLeft(t *testing.T) {
+	path := []string{"Installation", "From source"}
+	if got, title := breadcrumb("README.md", path, 80); got != "README.md › Installation › From source" || !title {
+		t.Errorf("expected the full breadcrumb, got %q (title %v)", got, title)
+	}
+	if got, title := breadcrumb("README.md", path, 30); got != "… › Installation › From source" || title {
+		t.Errorf("expected the title dropped first, got %q (title %v)", got, title)
+	}
+	if got, _ := breadcrumb("README.md", path, 17); got != "… › From source" {
+		t.Errorf("expected only the innermost section, got %q", got)
+	}
+	got, _ := breadcrumb("README.md", path, 8)
+	if got != "… source" {
+		t.Errorf("expected the innermost section cut from the left, got %q", got)
+	}
+}
+
+// model tests
+
+func TestModel_HeaderBreadcrumb(t *testing.T) {
+	md := "# Guide\n\n## Installation\n\n" + strings.Repeat("Step.\n\n", 30) + "## Usage\n\nRun it.\n"
+	m := newModel("README.md", md, "notty")
+	tm, _ := m.Update(tea.WindowSizeMsg{Width: 60, Height: 10})
+	m = tm.(model)
+	if header := strings.SplitN(m.View(), "\n", 2)[0]; strings.Contains(header, "›") {
+		t.Errorf("expected no breadcrumb above the first heading, got %q", header)
+	}
+	m.viewport.SetYOffset(10)
+	if header := strings.SplitN(m.View(), "\n", 2)[0]; !strings.Contains(header, "README.md › Guide › Installation") {
+		t.Errorf("expected the section path in the header, got %q", header)
+	}
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Private-use runes that carry inline HTML through glamour. Each is one cell
+// wide, so word wrapping sees the same width as the final output.
+const (
+	kbdOpen   = "\ue000"
+	kbdClose  = "\ue001"
+	lineBreak = "\ue002"
+)
+
+// detailsOpenRe matches the line opening a <details> block.
+var detailsOpenRe = regexp.MustCompile(`(?i)^\s*<details(?:\s[^>]*)?>`)
+
+// detailsTagRe matches opening and closing <details> tags, for nesting.
+var detailsTagRe = regexp.MustCompile(`(?i)<(/?)details(?:\s[^>]*)?>`)
+
+// summaryRe matches a <summary> element. Group 1 = summary text.
+var summaryRe = regexp.MustCompile(`(?is)<summary(?:\s[^>]*)?>(.*?)</summary>`)
+
+// centerOpenRe matches the line opening a centered block such as
+// <p align="center">. Group 1 = tag name.
+var centerOpenRe = regexp.MustCompile(`(?i)^\s*<(p|div|h[1-6])\s[^>]*align\s*=\s*["']?center["']?[^>]*>`)
+
+// htmlBlockPlaceholderRe matches an INCIPIT_HTML_N placeholder. Group 1 = index.
+var htmlBlockPlaceholderRe = regexp.MustCompile(`INCIPIT_HTML_(\d+)`)
+
+// detailsSummaryRe matches the summary line of a top-level <details> block in
+// ANSI-stripped rendered output.
+var detailsSummaryRe = regexp.MustCompile(`^  [▸▾] `)
+
+var (
+	htmlCommentRe = regexp.MustCompile(`(?s)<!--.*?-->`)
+	kbdRe         = regexp.MustCompile(`(?is)<kbd(?:\s[^>]*)?>(.*?)</kbd>`)
+	subRe         = regexp.MustCompile(`(?is)<sub>(.*?)</sub>`)
+	supRe         = regexp.MustCompile(`(?is)<sup>(.*?)</sup>`)
+	brRe          = regexp.MustCompile(`(?i)<br\s*/?>`)
+	boldTagRe     = regexp.MustCompile(`(?is)<(?:b|strong)>(.*?)</(?:b|strong)>`)
+	italicTagRe   = regexp.MustCompile(`(?is)<(?:i|em)>(.*?)</(?:i|em)>`)
+	codeTagRe     = regexp.MustCompile(`(?is)<code>(.*?)</code>`)
+	strikeTagRe   = regexp.MustCompile(`(?is)<(?:s|del|strike)>(.*?)</(?:s|del|strike)>`)
+	anchorTagRe   = regexp.MustCompile(`(?is)<a\s[^>]*href\s*=\s*["']([^"']*)["'][^>]*>(.*?)</a>`)
+	imgTagRe      = regexp.MustCompile(`(?i)<img\s[^>]*>`)
+	htmlAttrRe    = regexp.MustCompile(`(?i)\b(src|alt)\s*=\s*["']([^"']*)["']`)
+	anyTagRe      = regexp.MustCompile(`</?[A-Za-z][A-Za-z0-9-]*(?:\s[^<>]*)?/?>`)
+)
+
+type htmlBlock struct {
+	kind    string // "details" or "center"
+	summary string // details only
+	body    string // markdown
+}
+
+// extractHTMLBlocks pulls <details> blocks and centered <p>/<div>/<hN> blocks
+// out of md, replacing each with a unique placeholder paragraph, and returns
+// the modified prose plus the blocks. Fenced code is left alone.
+func extractHTMLBlocks(md string) (string, []htmlBlock) {
+	var blocks []htmlBlock
+	var out []string
+	lines := strings.Split(md, "\n")
+	fence := ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if sub := fenceRe.FindStringSubmatch(line); sub != nil {
+			if fence == "" {
+				fence = sub[1]
+			} else if sub[1] == fence {
+				fence = ""
+			}
+		}
+		if fence != "" {
+			out = append(out, line)
+			continue
+		}
+
+		if detailsOpenRe.MatchString(line) {
+			end, ok := closingDetails(lines, i)
+			if !ok {
+				out = append(out, line)
+				continue
+			}
+			blocks = append(blocks, parseDetails(strings.Join(lines[i:end+1], "\n")))
+			out = append(out, fmt.Sprintf("\nINCIPIT_HTML_%d\n", len(blocks)-1))
+			i = end
+			continue
+		}
+
+		if sub := centerOpenRe.FindStringSubmatch(line); sub != nil {
+			tag := strings.ToLower(sub[1])
+			closeTag := "</" + tag + ">"
+			end := -1
+			for j := i; j < len(lines); j++ {
+				if strings.Contains(strings.ToLower(lines[j]), closeTag) {
+					end = j
+					break
+				}
+			}
+			if end < 0 {
+				out = append(out, line)
+				continue
+			}
+			blocks = append(blocks, parseCentered(strings.Join(lines[i:end+1], "\n"), tag))
+			out = append(out, fmt.Sprintf("\nINCIPIT_HTML_%d\n", len(blocks)-1))
+			i = end
+			continue
+		}
+
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n"), blocks
+}
+
+// closingDetails returns the index of the line closing the <details> block
+// opened on lines[start]. Tags inside fenced code do not count.
+func closingDetails(lines []string, start int) (int, bool) {
+	depth := 0
+	fence := ""
+	for j := start; j < len(lines); j++ {
+		if sub := fenceRe.FindStringSubmatch(lines[j]); sub != nil {
+			if fence == "" {
+				fence = sub[1]
+			} else if sub[1] == fence {
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+		for _, m := range detailsTagRe.FindAllStringSubmatch(lines[j], -1) {
+			if m[1] == "" {
+				depth++
+			} else {
+				depth--
+			}
+		}
+		if depth <= 0 {
+			return j, true
+		}
+	}
+	return 0, false
+}
+
+// parseDetails splits the source of a <details> element into its summary and
+// markdown body.
+func parseDetails(src string) htmlBlock {
+	open := detailsTagRe.FindStringIndex(src)
+	src = src[open[1]:]
+	if i := strings.LastIndex(strings.ToLower(src), "</details>"); i >= 0 {
+		src = src[:i]
+	}
+	summary := "Details"
+	if loc := summaryRe.FindStringSubmatchIndex(src); loc != nil {
+		summary = strings.TrimSpace(src[loc[2]:loc[3]])
+		src = src[:loc[0]] + src[loc[1]:]
+	}
+	summary = stripInlineMarkdown(anyTagRe.ReplaceAllString(convertInlineHTML(summary), ""))
+	return htmlBlock{kind: "details", summary: summary, body: dedentHTML(src)}
+}
+
+// parseCentered turns the source of a centered element into markdown.
+func parseCentered(src, tag string) htmlBlock {
+	open := centerOpenRe.FindStringIndex(src)
+	src = src[open[1]:]
+	if i := strings.LastIndex(strings.ToLower(src), "</"+tag+">"); i >= 0 {
+		src = src[:i]
+	}
+	body := dedentHTML(src)
+	if tag[0] == 'h' {
+		level, _ := strconv.Atoi(tag[1:])
+		body = strings.Repeat("#", level) + " " + strings.Join(strings.Fields(body), " ")
+	}
+	return htmlBlock{kind: "center", body: body}
+}
+
+// dedentHTML strips the indentation HTML authors use inside block elements, so
+// it is not read as an indented code block, and trims surrounding blank lines.
+func dedentHTML(src string) string {
+	lines := strings.Split(src, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// convertInlineHTML rewrites common inline HTML in md as markdown, or as the
+// private-use markers finishInlineHTML styles after rendering. Comments and
+// unknown tags are dropped, keeping their text. Code spans are left alone.
+func convertInlineHTML(md string) string {
+	md = htmlCommentRe.ReplaceAllString(md, "")
+	lines := strings.Split(md, "\n")
+	for i, line := range lines {
+		if !strings.Contains(line, "<") {
+			continue
+		}
+		lines[i] = mapOutsideCodeSpans(line, convertInlineTags)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// convertInlineTags converts the HTML tags in a fragment of one line.
+func convertInlineTags(s string) string {
+	s = kbdRe.ReplaceAllStringFunc(s, func(m string) string {
+		key := strings.TrimSpace(kbdRe.FindStringSubmatch(m)[1])
+		return kbdOpen + strings.ReplaceAll(key, " ", "\u00a0") + kbdClose
+	})
+	s = subRe.ReplaceAllStringFunc(s, func(m string) string {
+		return script(subRe.FindStringSubmatch(m)[1], false)
+	})
+	s = supRe.ReplaceAllStringFunc(s, func(m string) string {
+		return script(supRe.FindStringSubmatch(m)[1], true)
+	})
+	s = brRe.ReplaceAllString(s, lineBreak)
+	s = boldTagRe.ReplaceAllString(s, "**$1**")
+	s = italicTagRe.ReplaceAllString(s, "*$1*")
+	s = codeTagRe.ReplaceAllString(s, "`$1`")
+	s = strikeTagRe.ReplaceAllString(s, "~~$1~~")
+	s = anchorTagRe.ReplaceAllString(s, "[$2]($1)")
+	s = imgTagRe.ReplaceAllStringFunc(s, func(m string) string {
+		attrs := map[string]string{}
+		for _, a := range htmlAttrRe.FindAllStringSubmatch(m, -1) {
+			attrs[strings.ToLower(a[1])] = a[2]
+		}
+		if attrs["src"] == "" {
+			return attrs["alt"]
+		}
+		return fmt.Sprintf("![%s](%s)", attrs["alt"], attrs["src"])
+	})
+	return anyTagRe.ReplaceAllString(s, "")
+}
+
+// renderKeycap renders a <kbd> key label as a keycap.
+func renderKeycap(key, style string) string {
+	if style == "notty" {
+		return "[" + key + "]"
+	}
+	fg, bg := "252", "238"
+	if style == "light" {
+		fg, bg = "235", "252"
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(fg)).Background(lipgloss.Color(bg)).Render(" " + key + " ")
+}
+
+// finishInlineHTML styles the keycap markers in rendered and breaks lines at
+// the line break markers, keeping each line's left margin.
+func finishInlineHTML(rendered, style string) string {
+	if !strings.ContainsAny(rendered, kbdOpen+lineBreak) {
+		return rendered
+	}
+	var out []string
+	for _, line := range strings.Split(rendered, "\n") {
+		for {
+			start := strings.Index(line, kbdOpen)
+			if start < 0 {
+				break
+			}
+			end := strings.Index(line[start:], kbdClose)
+			if end < 0 {
+				break
+			}
+			key := strings.ReplaceAll(line[start+len(kbdOpen):start+end], "\u00a0", " ")
+			line = line[:start] + renderKeycap(key, style) + line[start+end+len(kbdClose):]
+		}
+
+		plain := stripANSI(line)
+		margin := strings.Repeat(" ", len(plain)-len(strings.TrimLeft(plain, " ")))
+		for {
+			i := strings.Index(line, lineBreak)
+			if i < 0 {
+				break
+			}
+			head, tail := line[:i], line[i+len(lineBreak):]
+			if strings.TrimSpace(stripANSI(tail)) == "" {
+				line = head + tail
+				break
+			}
+			if strings.TrimSpace(stripANSI(head)) != "" {
+				if strings.Contains(head, "\x1b[") {
+					head += "\x1b[0m"
+				}
+				out = append(out, head)
+			}
+			line = margin + strings.TrimLeft(tail, " ")
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// centerLines centers each line of rendered within width.
+func centerLines(rendered string, width int) string {
+	lines := strings.Split(rendered, "\n")
+	for i, line := range lines {
+		plain := stripANSI(line)
+		trimmed := strings.TrimSpace(plain)
+		if trimmed == "" {
+			lines[i] = ""
+			continue
+		}
+		lead := lipgloss.Width(plain[:len(plain)-len(strings.TrimLeft(plain, " "))])
+		w := lipgloss.Width(trimmed)
+		lines[i] = strings.Repeat(" ", max(0, (width-w)/2)) + ansi.Cut(line, lead, lead+w)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderDetailsSummary renders the summary line of a <details> block with its
+// open/closed marker.
+func renderDetailsSummary(summary string, open bool, style string) string {
+	marker := "▸ "
+	if open {
+		marker = "▾ "
+	}
+	if style == "notty" {
+		return "  " + marker + summary
+	}
+	fg, _, _ := headerColors(4, style)
+	return "  " + lipgloss.NewStyle().Foreground(lipgloss.Color(fg)).Bold(true).Render(marker+summary)
+}
+
+// renderHTMLBlock renders a details or centered block.
+func renderHTMLBlock(b htmlBlock, open bool, width int, style string, opts renderOptions) string {
+	inner := nestedOptions(opts)
+	if b.kind == "center" {
+		return centerLines(renderDocument(b.body, style, width, inner), width)
+	}
+	summary := renderDetailsSummary(b.summary, open, style)
+	if !open || strings.TrimSpace(b.body) == "" {
+		return summary
+	}
+	out := []string{summary}
+	body := renderDocument(b.body, style, max(width-2, 10), inner)
+	for _, line := range trimBlankLines(strings.Split(body, "\n")) {
+		out = append(out, "  "+line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// injectHTMLBlocks replaces INCIPIT_HTML_N placeholder lines in rendered with
+// the rendered block. Details blocks are numbered in document order for
+// opts.openDetails.
+func injectHTMLBlocks(rendered string, blocks []htmlBlock, width int, style string, opts renderOptions) string {
+	details := map[int]int{} // block index → details index
+	for i, b := range blocks {
+		if b.kind == "details" {
+			details[i] = len(details)
+		}
+	}
+	lines := strings.Split(rendered, "\n")
+	for i, line := range lines {
+		sub := htmlBlockPlaceholderRe.FindStringSubmatch(stripANSI(line))
+		if sub == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(sub[1])
+		if n >= len(blocks) {
+			continue
+		}
+		open := !opts.collapseDetails || opts.openDetails[details[n]]
+		lines[i] = renderHTMLBlock(blocks[n], open, width, style, opts)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// detailsAt returns the index of the first top-level details summary shown in
+// viewport rows [from, to), counted over all ANSI-stripped rendered lines so
+// summaries in folded sections keep their place. rows maps each viewport row
+// to its rendered line.
+func detailsAt(lines []string, rows []int, from, to int) (int, bool) {
+	for _, line := range rows[min(from, len(rows)):min(to, len(rows))] {
+		if !detailsSummaryRe.MatchString(lines[line]) {
+			continue
+		}
+		n := 0
+		for _, l := range lines[:line] {
+			if detailsSummaryRe.MatchString(l) {
+				n++
+			}
+		}
+		return n, true
+	}
+	return 0, false
+}
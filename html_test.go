@@ -0,0 +1,141 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// extractHTMLBlocks tests
+
+func TestExtractHTMLBlocks_Details(t *testing.T) {
+	md := "Intro\n\n<details>\n<summary>More <b>info</b></summary>\n\nBody text.\n\n```html\n</details>\n```\n\n</details>\n\nOutro"
+	prose, blocks := extractHTMLBlocks(md)
+	if len(blocks) != 1 || blocks[0].kind != "details" {
+		t.Fatalf("expected one details block, got %+v", blocks)
+	}
+	if blocks[0].summary != "More info" {
+		t.Errorf("unexpected summary %q", blocks[0].summary)
+	}
+	if !strings.Contains(blocks[0].body, "Body text.") || !strings.Contains(blocks[0].body, "```html\n</details>\n```") {
+		t.Errorf("expected body to keep the fenced code, got %q", blocks[0].body)
+	}
+	if !strings.Contains(prose, "INCIPIT_HTML_0") || !strings.Contains(prose, "Outro") {
+		t.Errorf("unexpected prose %q", prose)
+	}
+}
+
+func TestExtractHTMLBlocks_CenteredHeading(t *testing.T) {
+	_, blocks := extractHTMLBlocks("<h1 align=\"center\">\n  My Project\n</h1>\n")
+	if len(blocks) != 1 || blocks[0].kind != "center" || blocks[0].body != "# My Project" {
+		t.Errorf("expected centered heading converted to markdown, got %+v", blocks)
+	}
+}
+
+func TestExtractHTMLBlocks_IgnoresFencedCode(t *testing.T) {
+	md := "```\n<details>\n<summary>x</summary>\n</details>\n```\n"
+	if _, blocks := extractHTMLBlocks(md); len(blocks) != 0 {
+		t.Errorf("expected no blocks inside code, got %+v", blocks)
+	}
+}
+
+// inline tag tests
+
+func TestConvertInlineHTML(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"H<sub>2</sub>O and x<sup>2</sup>", "H₂O and x²"},
+		{"<b>bold</b> <em>it</em> <code>c</code> <del>gone</del>", "**bold** *it* `c` ~~gone~~"},
+		{`<a href="https://x.dev">site</a>`, "[site](https://x.dev)"},
+		{`<img src="a.png" alt="A">`, "![A](a.png)"},
+		{`<span class="x">kept</span> <custom-tag>text</custom-tag>`, "kept text"},
+		{"before <!-- note --> after", "before  after"},
+		{"`<b>code</b>` stays", "`<b>code</b>` stays"},
+		{"see <https://example.com>", "see <https://example.com>"},
+	}
+	for _, tc := range cases {
+		if got := convertInlineHTML(tc.in); got != tc.want {
+			t.Errorf("convertInlineHTML(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestFinishInlineHTML_KeycapsAndBreaks(t *testing.T) {
+	in := "  Press " + kbdOpen + "Page Up" + kbdClose + " now" + lineBreak + "next line"
+	got := finishInlineHTML(in, "notty")
+	if got != "  Press [Page Up] now\n  next line" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestCenterLines(t *testing.T) {
+	if got := centerLines("  hello   ", 21); got != strings.Repeat(" ", 8)+"hello" {
+		t.Errorf("unexpected centering %q", got)
+	}
+}
+
+// end-to-end tests
+
+func TestRenderDocument_DetailsCollapsed(t *testing.T) {
+	md := "<details>\n<summary>Secret</summary>\n\nHidden body.\n</details>\n"
+	closed := stripANSI(renderDocument(md, "dark", 60, renderOptions{collapseDetails: true}))
+	if !strings.Contains(closed, "▸ Secret") || strings.Contains(closed, "Hidden body") {
+		t.Errorf("expected collapsed details, got %q", closed)
+	}
+	open := stripANSI(renderDocument(md, "dark", 60, renderOptions{collapseDetails: true, openDetails: map[int]bool{0: true}}))
+	if !strings.Contains(open, "▾ Secret") || !strings.Contains(open, "Hidden body") {
+		t.Errorf("expected opened details, got %q", open)
+	}
+	if all := stripANSI(renderMarkdown(md, "dark", 60)); !strings.Contains(all, "Hidden body") {
+		t.Errorf("expected details open outside the pager, got %q", all)
+	}
+}
+
+func TestRenderMarkdown_InlineHTML(t *testing.T) {
+	out := stripANSI(renderMarkdown("Press <kbd>Ctrl</kbd>+<kbd>C</kbd>.<br>Done <span>here</span>.", "dark", 60))
+	for _, want := range []string{" Ctrl + C .", "Done here."} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got %q", want, out)
+		}
+	}
+	if strings.Contains(out, "<") || strings.ContainsAny(out, kbdOpen+kbdClose+lineBreak) {
+		t.Errorf("expected no tags or markers left, got %q", out)
+	}
+}
+
+// model tests
+
+func TestModel_ToggleDetails(t *testing.T) {
+	md := "<details>\n<summary>Secret</summary>\n\nHidden body.\n</details>\n"
+	var tm tea.Model = newModel("doc.md", md, "notty")
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 60, Height: 20})
+	if strings.Contains(tm.View(), "Hidden body") {
+		t.Fatal("expected details collapsed in the pager")
+	}
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	if !strings.Contains(tm.View(), "Hidden body") {
+		t.Error("expected o to open the details block")
+	}
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	if strings.Contains(tm.View(), "Hidden body") {
+		t.Error("expected o to close it again")
+	}
+}
+
+func TestModel_ToggleDetailsAfterFold(t *testing.T) {
+	md := "# A\n\n<details>\n<summary>First</summary>\n\nFirst body.\n</details>\n\n# B\n\n<details>\n<summary>Second</summary>\n\nSecond body.\n</details>\n"
+	var tm tea.Model = newModel("doc.md", md, "notty")
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 60, Height: 30})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("z")})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	if strings.Contains(tm.View(), "First") {
+		t.Fatal("expected za to fold section A")
+	}
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	if !tm.(model).opts.openDetails[1] || tm.(model).opts.openDetails[0] {
+		t.Errorf("expected o to open the details in section B, got %v", tm.(model).opts.openDetails)
+	}
+	if !strings.Contains(tm.View(), "Second body") {
+		t.Errorf("expected the second details block open, got %q", tm.View())
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// imageRe matches a paragraph holding a single image. Group 1 = alt text,
+// group 2 = path.
+var imageRe = regexp.MustCompile(`^\s{0,3}!\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)\s*$`)
+
+// imagePlaceholderRe matches an INCIPIT_IMAGE_N placeholder. Group 1 = index.
+var imagePlaceholderRe = regexp.MustCompile(`INCIPIT_IMAGE_(\d+)`)
+
+// Assumed terminal cell size in pixels, used to turn image dimensions into
+// columns and rows.
+const (
+	cellWidthPx  = 10
+	cellHeightPx = 20
+)
+
+type imageBlock struct {
+	alt  string
+	path string // as written in the document
+}
+
+// detectGraphics guesses the inline image protocol supported by the terminal
+// from the environment, returning "" when none is known.
+func detectGraphics() string {
+	term := os.Getenv("TERM")
+	program := os.Getenv("TERM_PROGRAM")
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || term == "xterm-ghostty" || program == "ghostty":
+		return "kitty"
+	case program == "iTerm.app" || program == "WezTerm" || os.Getenv("LC_TERMINAL") == "iTerm2":
+		return "iterm"
+	case strings.Contains(term, "sixel") || strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm") || program == "mlterm":
+		return "sixel"
+	}
+	return ""
+}
+
+// isLocalImage reports whether path refers to a file rather than a URL.
+func isLocalImage(path string) bool {
+	return !strings.Contains(path, "://") && !strings.HasPrefix(path, "//") && !strings.HasPrefix(path, "data:")
+}
+
+// extractImages pulls paragraphs consisting of a single local image out of md,
+// replacing each with a unique placeholder paragraph, and returns the modified
+// prose plus the images. Remote images and images inside text are left for
+// glamour.
+func extractImages(md string) (string, []imageBlock) {
+	var images []imageBlock
+	lines := strings.Split(md, "\n")
+	for i, line := range lines {
+		sub := imageRe.FindStringSubmatch(line)
+		if sub == nil || !isLocalImage(sub[2]) {
+			continue
+		}
+		lines[i] = fmt.Sprintf("\nINCIPIT_IMAGE_%d\n", len(images))
+		images = append(images, imageBlock{alt: sub[1], path: sub[2]})
+	}
+	return strings.Join(lines, "\n"), images
+}
+
+// imageCells returns the columns and rows an image of w×h pixels occupies when
+// scaled down to fit width columns.
+func imageCells(w, h, width int) (cols, rows int) {
+	cols = min(max(width, 1), (w+cellWidthPx-1)/cellWidthPx)
+	cols = max(cols, 1)
+	rows = (cols*cellWidthPx*h + w*cellHeightPx/2) / (w * cellHeightPx)
+	return cols, max(rows, 1)
+}
+
+// renderImage draws img inline using the given protocol, as rows lines of
+// output: the escape sequence followed by blank lines reserving the space the
+// image covers. An unknown protocol returns "".
+func renderImage(img image.Image, protocol string, width int) string {
+	b := img.Bounds()
+	cols, rows := imageCells(b.Dx(), b.Dy(), width)
+	var esc string
+	switch protocol {
+	case "kitty":
+		esc = kittyImage(img, cols, rows)
+	case "iterm":
+		esc = itermImage(img, cols, rows)
+	case "sixel":
+		esc = sixelImage(img, cols*cellWidthPx, rows*cellHeightPx)
+	default:
+		return ""
+	}
+	return esc + strings.Repeat("\n", rows-1)
+}
+
+// encodePNG returns img as PNG data.
+func encodePNG(img image.Image) []byte {
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// kittyImage returns the Kitty graphics protocol sequence that displays img
+// over cols×rows cells without moving the cursor.
+func kittyImage(img image.Image, cols, rows int) string {
+	data := base64.StdEncoding.EncodeToString(encodePNG(img))
+	const chunk = 4096
+	var b strings.Builder
+	for i := 0; i < len(data); i += chunk {
+		end := min(i+chunk, len(data))
+		more := 0
+		if end < len(data) {
+			more = 1
+		}
+		if i == 0 {
+			fmt.Fprintf(&b, "\x1b_Ga=T,f=100,q=2,C=1,c=%d,r=%d,m=%d;%s\x1b\\", cols, rows, more, data[i:end])
+		} else {
+			fmt.Fprintf(&b, "\x1b_Gm=%d;%s\x1b\\", more, data[i:end])
+		}
+	}
+	return b.String()
+}
+
+// itermImage returns the iTerm2 inline image sequence that displays img over
+// cols×rows cells.
+func itermImage(img image.Image, cols, rows int) string {
+	data := encodePNG(img)
+	return fmt.Sprintf("\x1b]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=0:%s\a",
+		len(data), cols, rows, base64.StdEncoding.EncodeToString(data))
+}
+
+// sixelImage returns img scaled to w×h pixels as a sixel sequence, quantised
+// to a 6×6×6 color cube.
+func sixelImage(img image.Image, w, h int) string {
+	b := img.Bounds()
+	idx := make([]int, w*h) // palette index per pixel, -1 for transparent
+	used := map[int]bool{}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, a := img.At(b.Min.X+x*b.Dx()/w, b.Min.Y+y*b.Dy()/h).RGBA()
+			if a < 0x8000 {
+				idx[y*w+x] = -1
+				continue
+			}
+			c := int(r*5/0xffff)*36 + int(g*5/0xffff)*6 + int(bl*5/0xffff)
+			idx[y*w+x] = c
+			used[c] = true
+		}
+	}
+
+	var s strings.Builder
+	fmt.Fprintf(&s, "\x1bP0;1q\"1;1;%d;%d", w, h)
+	for c := 0; c < 216; c++ {
+		if used[c] {
+			fmt.Fprintf(&s, "#%d;2;%d;%d;%d", c, c/36*20, c/6%6*20, c%6*20)
+		}
+	}
+	for band := 0; band < h; band += 6 {
+		first := true
+		for c := 0; c < 216; c++ {
+			if !used[c] {
+				continue
+			}
+			row := make([]byte, w)
+			present := false
+			for x := 0; x < w; x++ {
+				bits := 0
+				for dy := 0; dy < 6 && band+dy < h; dy++ {
+					if idx[(band+dy)*w+x] == c {
+						bits |= 1 << dy
+					}
+				}
+				row[x] = byte(63 + bits)
+				present = present || bits != 0
+			}
+			if !present {
+				continue
+			}
+			if !first {
+				s.WriteByte('$')
+			}
+			first = false
+			fmt.Fprintf(&s, "#%d", c)
+			writeSixelRun(&s, row)
+		}
+		s.WriteByte('-')
+	}
+	s.WriteString("\x1b\\")
+	return s.String()
+}
+
+// writeSixelRun writes a row of sixel characters, run-length encoding repeats.
+func writeSixelRun(s *strings.Builder, row []byte) {
+	for i := 0; i < len(row); {
+		j := i
+		for j < len(row) && row[j] == row[i] {
+			j++
+		}
+		if n := j - i; n > 3 {
+			fmt.Fprintf(s, "!%d%c", n, row[i])
+		} else {
+			s.Write(row[i:j])
+		}
+		i = j
+	}
+}
+
+// renderImageFallback draws a framed text placeholder describing the image.
+func renderImageFallback(ib imageBlock, size string, width int, style string) string {
+	text := fmt.Sprintf("[image: %s — %s (%s)]", ib.alt, ib.path, size)
+	if ib.alt == "" {
+		text = fmt.Sprintf("[image: %s (%s)]", ib.path, size)
+	}
+	border := ""
+	if style != "notty" {
+		_, border = codeBlockColors(style)
+	}
+	return renderFrame("", []string{text}, min(width, len([]rune(text))+4), border)
+}
+
+// renderImageBlock draws ib inline when the protocol allows, else the text
+// placeholder.
+func renderImageBlock(ib imageBlock, width int, style string, opts renderOptions) string {
+	path := ib.path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(opts.baseDir, path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return renderImageFallback(ib, "not found", width, style)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return renderImageFallback(ib, "unreadable", width, style)
+	}
+	if out := renderImage(img, opts.graphics, width); out != "" {
+		return out
+	}
+	b := img.Bounds()
+	return renderImageFallback(ib, fmt.Sprintf("%dx%d", b.Dx(), b.Dy()), width, style)
+}
+
+// injectImages replaces INCIPIT_IMAGE_N placeholder lines in rendered with the
+// drawn image or its placeholder.
+func injectImages(rendered string, images []imageBlock, width int, style string, opts renderOptions) string {
+	lines := strings.Split(rendered, "\n")
+	for i, line := range lines {
+		sub := imagePlaceholderRe.FindStringSubmatch(stripANSI(line))
+		if sub == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(sub[1])
+		if n < len(images) {
+			lines[i] = renderImageBlock(images[n], width, style, opts)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// writeTestPNG writes a w×h two-color PNG into dir and returns its path.
+func writeTestPNG(t *testing.T, dir, name string, w, h int) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{255, 0, 0, 255}
+			if x >= w/2 {
+				c = color.RGBA{0, 0, 255, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// extractImages tests
+
+func TestExtractImages_LocalOnly(t *testing.T) {
+	md := "![Logo](img/logo.png)\n\n![Remote](https://example.com/a.png)\n\nText with ![inline](a.png) image."
+	prose, images := extractImages(md)
+	if len(images) != 1 || images[0].alt != "Logo" || images[0].path != "img/logo.png" {
+		t.Fatalf("expected only the local standalone image, got %+v", images)
+	}
+	if !strings.Contains(prose, "INCIPIT_IMAGE_0") || !strings.Contains(prose, "https://example.com/a.png") {
+		t.Errorf("unexpected prose %q", prose)
+	}
+}
+
+func TestImageCells_ScalesToWidth(t *testing.T) {
+	if cols, rows := imageCells(200, 100, 80); cols != 20 || rows != 5 {
+		t.Errorf("expected 20x5 cells for a small image, got %dx%d", cols, rows)
+	}
+	if cols, rows := imageCells(2000, 1000, 40); cols != 40 || rows != 10 {
+		t.Errorf("expected large image scaled to 40 columns, got %dx%d", cols, rows)
+	}
+}
+
+// protocol tests
+
+func TestRenderImage_Kitty(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 80))
+	out := renderImage(img, "kitty", 60)
+	if !strings.HasPrefix(out, "\x1b_Ga=T,f=100,q=2,C=1,c=10,r=4,") || !strings.Contains(out, "\x1b\\") {
+		t.Errorf("unexpected kitty sequence prefix: %q", out[:min(len(out), 40)])
+	}
+	if lines := strings.Split(out, "\n"); len(lines) != 4 {
+		t.Errorf("expected 4 reserved lines, got %d", len(lines))
+	}
+	if stripANSI(out) != "\n\n\n" || lipgloss.Width(out) != 0 {
+		t.Errorf("expected escape to be invisible to search and width, got %q", stripANSI(out))
+	}
+}
+
+func TestRenderImage_ITerm(t *testing.T) {
+	out := renderImage(image.NewRGBA(image.Rect(0, 0, 100, 80)), "iterm", 60)
+	if !strings.HasPrefix(out, "\x1b]1337;File=inline=1;") || !strings.Contains(out, "width=10;height=4") {
+		t.Errorf("unexpected iTerm2 sequence: %q", out[:min(len(out), 60)])
+	}
+}
+
+func TestRenderImage_Sixel(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	for x := 0; x < 20; x++ {
+		for y := 0; y < 20; y++ {
+			img.Set(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+	out := renderImage(img, "sixel", 60)
+	seq := strings.Split(out, "\n")[0]
+	if !strings.HasPrefix(seq, "\x1bP0;1q\"1;1;20;20") || !strings.HasSuffix(seq, "\x1b\\") {
+		t.Errorf("unexpected sixel framing: %q", seq)
+	}
+	if !strings.Contains(seq, "#215;2;100;100;100") || !strings.Contains(seq, "!20~") {
+		t.Errorf("expected white palette entry and run-length encoded row, got %q", seq)
+	}
+}
+
+// end-to-end tests
+
+func TestRenderDocument_ImageFallback(t *testing.T) {
+	dir := t.TempDir()
+	writeTestPNG(t, dir, "logo.png", 64, 32)
+	out := stripANSI(renderDocument("![Logo](logo.png)\n\n![Gone](gone.png)", "dark", 80, renderOptions{baseDir: dir}))
+	for _, want := range []string{"[image: Logo — logo.png (64x32)]", "[image: Gone — gone.png (not found)]", "╭"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got %q", want, out)
+		}
+	}
+}
+
+func TestRenderDocument_ImageInline(t *testing.T) {
+	dir := t.TempDir()
+	writeTestPNG(t, dir, "logo.png", 400, 200)
+	out := renderDocument("Before\n\n![Logo](logo.png)\n\nAfter", "dark", 80, renderOptions{baseDir: dir, graphics: "kitty"})
+	if !strings.Contains(out, "\x1b_Ga=T,f=100") {
+		t.Fatal("expected kitty escape in output")
+	}
+	lines := strings.Split(stripANSI(out), "\n")
+	before, after := -1, -1
+	for i, l := range lines {
+		if strings.Contains(l, "Before") {
+			before = i
+		}
+		if strings.Contains(l, "After") {
+			after = i
+		}
+	}
+	if after-before < 10 {
+		t.Errorf("expected 10 image rows reserved between paragraphs, got %d lines", after-before)
+	}
+}
+
+func TestDetectGraphics(t *testing.T) {
+	for _, env := range []string{"KITTY_WINDOW_ID", "TERM", "TERM_PROGRAM", "LC_TERMINAL"} {
+		t.Setenv(env, "")
+	}
+	if got := detectGraphics(); got != "" {
+		t.Errorf("expected no protocol, got %q", got)
+	}
+	t.Setenv("TERM_PROGRAM", "iTerm.app")
+	if got := detectGraphics(); got != "iterm" {
+		t.Errorf("expected iterm, got %q", got)
+	}
+	t.Setenv("TERM", "xterm-kitty")
+	if got := detectGraphics(); got != "kitty" {
+		t.Errorf("expected kitty, got %q", got)
+	}
+}
+
+func TestRenderDocument_ImageInNestedBlocks(t *testing.T) {
+	dir := t.TempDir()
+	writeTestPNG(t, dir, "logo.png", 64, 32)
+	md := "> [!NOTE]\n> ![Logo](logo.png)\n\nTerm\n: ![Logo](logo.png)\n"
+	out := stripANSI(renderDocument(md, "dark", 80, renderOptions{baseDir: dir, definitionLists: true}))
+	if n := strings.Count(out, "logo.png (64x32)"); n != 2 {
+		t.Errorf("expected the image resolved against baseDir in the alert and the definition, got %d in %q", n, out)
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// inlineLinkRe matches an inline link or image whose text may hold one
+	// level of brackets, such as a badge image. Group 1 = destination, in
+	// angle brackets when it has spaces.
+	inlineLinkRe = regexp.MustCompile(`!?\[(?:[^\[\]\\]|\\.|\[(?:[^\[\]\\]|\\.)*\])*\]\(\s*(<[^<>\n]*>|[^)\s]*)(?:\s+(?:"[^"]*"|'[^']*'))?\s*\)`)
+
+	// refDefRe matches a link reference definition. Group 1 = destination.
+	refDefRe = regexp.MustCompile(`^ {0,3}\[[^\]^][^\]]*\]:\s*<?([^\s>]+)>?`)
+
+	// autolinkRe matches an autolink. Group 1 = URL.
+	autolinkRe = regexp.MustCompile(`<(https?://[^\s<>]+)>`)
+
+	// htmlLinkRe matches an href or src attribute. Group 1 = URL.
+	htmlLinkRe = regexp.MustCompile(`(?i)\b(?:href|src)\s*=\s*["']([^"']*)["']`)
+
+	// htmlAnchorRe matches an id or name attribute that a fragment can
+	// point at. Group 1 = anchor.
+	htmlAnchorRe = regexp.MustCompile(`(?i)\b(?:id|name)\s*=\s*["']([^"']+)["']`)
+
+	// schemeRe matches a URL scheme such as "https:" or "mailto:".
+	schemeRe = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+)
+
+// linkRef is a link destination found in a document. Line and col are
+// 1-based.
+type linkRef struct {
+	line, col int
+	dest      string
+}
+
+// scanLinks returns the link destinations of a file's contents outside code:
+// inline links and images, reference definitions, autolinks and HTML href and
+// src attributes. Lines count from the start of the file.
+func scanLinks(content string) []linkRef {
+	body, _ := extractFrontMatter(content)
+	offset := strings.Count(content[:len(content)-len(body)], "\n")
+	var links []linkRef
+	fence := ""
+	for i, line := range strings.Split(body, "\n") {
+		if sub := fenceRe.FindStringSubmatch(line); sub != nil {
+			if fence == "" {
+				fence = sub[1]
+			} else if sub[1] == fence {
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+		masked := maskCodeSpans(line)
+		add := func(start, end int) {
+			if end-start >= 2 && line[start] == '<' && line[end-1] == '>' {
+				start, end = start+1, end-1
+			}
+			links = append(links, linkRef{line: i + offset + 1, col: column(line, start), dest: line[start:end]})
+		}
+		for _, re := range []*regexp.Regexp{inlineLinkRe, refDefRe, autolinkRe, htmlLinkRe} {
+			for _, loc := range re.FindAllStringSubmatchIndex(masked, -1) {
+				add(loc[2], loc[3])
+				if re != inlineLinkRe {
+					continue
+				}
+				// An image inside the link text, as in [![CI](badge.svg)](ci.md).
+				text := loc[0] + strings.Index(masked[loc[0]:], "[") + 1
+				for _, in := range inlineLinkRe.FindAllStringSubmatchIndex(masked[text:loc[2]], -1) {
+					add(text+in[2], text+in[3])
+				}
+			}
+		}
+	}
+	sort.SliceStable(links, func(a, b int) bool {
+		if links[a].line != links[b].line {
+			return links[a].line < links[b].line
+		}
+		return links[a].col < links[b].col
+	})
+	return links
+}
+
+// documentAnchors returns the fragments a file's contents can be linked to:
+// its heading slugs, computed the way GitHub does, and HTML id and name
+// attributes.
+func documentAnchors(content string) map[string]bool {
+	anchors := map[string]bool{}
+	for _, h := range documentHeadings(content) {
+		anchors[h.slug] = true
+	}
+	for _, sub := range htmlAnchorRe.FindAllStringSubmatch(content, -1) {
+		anchors[sub[1]] = true
+	}
+	return anchors
+}
+
+// brokenLink is a link that does not resolve.
+type brokenLink struct {
+	file   string
+	ref    linkRef
+	reason string
+}
+
+func (b brokenLink) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", b.file, b.ref.line, b.ref.col, b.ref.dest, b.reason)
+}
+
+// linkChecker resolves links against the filesystem and, when external is
+// set, the network.
+type linkChecker struct {
+	root     string // directory "/"-rooted links resolve against
+	external bool
+	client   *http.Client
+
+	anchors map[string]map[string]bool // anchors of each markdown file read so far
+}
+
+// newLinkChecker returns a checker; checkLinks sets its root.
+func newLinkChecker(external bool) *linkChecker {
+	return &linkChecker{
+		external: external,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		anchors:  map[string]map[string]bool{},
+	}
+}
+
+// fileAnchors returns the anchors of a markdown file, reading it once.
+func (c *linkChecker) fileAnchors(path string) (map[string]bool, error) {
+	if a, ok := c.anchors[path]; ok {
+		return a, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c.anchors[path] = documentAnchors(string(data))
+	return c.anchors[path], nil
+}
+
+// checkLocal returns why a relative link from file is broken, or "" when it
+// resolves.
+func (c *linkChecker) checkLocal(file, dest string) string {
+	target, fragment, _ := strings.Cut(dest, "#")
+	target, _, _ = strings.Cut(target, "?")
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	path := file
+	switch {
+	case strings.HasPrefix(target, "/"):
+		path = filepath.Join(c.root, filepath.FromSlash(target))
+	case target != "":
+		path = filepath.Join(filepath.Dir(file), filepath.FromSlash(target))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "no such file or directory"
+	}
+	if fragment == "" || info.IsDir() || !isMarkdownFile(path) {
+		return ""
+	}
+	anchors, err := c.fileAnchors(path)
+	if err != nil {
+		return err.Error()
+	}
+	if unescaped, err := url.PathUnescape(fragment); err == nil {
+		fragment = unescaped
+	}
+	if !anchors[fragment] && !anchors[strings.ToLower(fragment)] {
+		return fmt.Sprintf("no heading with anchor #%s", fragment)
+	}
+	return ""
+}
+
+// checkURL returns why an external URL is broken, or "" when it answers.
+// Servers that refuse HEAD are asked again with GET.
+func (c *linkChecker) checkURL(u string) string {
+	resp, err := c.client.Head(u)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = c.client.Get(u)
+	}
+	if err != nil {
+		return err.Error()
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return resp.Status
+	}
+	return ""
+}
+
+// checkURLs checks external URLs concurrently and returns why each broken one
+// failed.
+func (c *linkChecker) checkURLs(urls []string) map[string]string {
+	const workers = 8
+	failed := map[string]string{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan string)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range queue {
+				if reason := c.checkURL(u); reason != "" {
+					mu.Lock()
+					failed[u] = reason
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, u := range urls {
+		queue <- u
+	}
+	close(queue)
+	wg.Wait()
+	return failed
+}
+
+// check returns the broken links of files in order.
+func (c *linkChecker) check(files []string) ([]brokenLink, error) {
+	var broken []brokenLink
+	type externalRef struct {
+		file string
+		ref  linkRef
+	}
+	var external []externalRef
+	seen := map[string]bool{}
+	var urls []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range scanLinks(string(data)) {
+			switch {
+			case ref.dest == "" || strings.HasPrefix(ref.dest, "//"):
+			case strings.HasPrefix(ref.dest, "http://") || strings.HasPrefix(ref.dest, "https://"):
+				if !c.external {
+					continue
+				}
+				external = append(external, externalRef{file, ref})
+				if !seen[ref.dest] {
+					seen[ref.dest] = true
+					urls = append(urls, ref.dest)
+				}
+			case schemeRe.MatchString(ref.dest):
+				// mailto:, tel: and the like cannot be checked.
+			default:
+				if reason := c.checkLocal(file, ref.dest); reason != "" {
+					broken = append(broken, brokenLink{file, ref, reason})
+				}
+			}
+		}
+	}
+	if len(urls) > 0 {
+		failed := c.checkURLs(urls)
+		for _, e := range external {
+			if reason, ok := failed[e.ref.dest]; ok {
+				broken = append(broken, brokenLink{e.file, e.ref, reason})
+			}
+		}
+	}
+	sort.SliceStable(broken, func(a, b int) bool {
+		if broken[a].file != broken[b].file {
+			return broken[a].file < broken[b].file
+		}
+		if broken[a].ref.line != broken[b].ref.line {
+			return broken[a].ref.line < broken[b].ref.line
+		}
+		return broken[a].ref.col < broken[b].ref.col
+	})
+	return broken, nil
+}
+
+// runCheckLinks implements "incipit check-links": it checks the links of a
+// markdown file, or of every markdown file under a directory, and prints the
+// broken ones. It returns 1 when there are any.
+func runCheckLinks(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("check-links", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	external := fs.Bool("external", false, "also check http and https links")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: incipit check-links [--external] <file.md|dir>\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	return checkLinks(fs.Arg(0), newLinkChecker(*external), stdout, stderr)
+}
+
+// checkLinks runs c over path, a markdown file or a directory, and prints the
+// broken links. It returns the exit status.
+func checkLinks(path string, c *linkChecker, stdout, stderr io.Writer) int {
+	info, err := os.Stat(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "incipit: %s\n", err)
+		return 2
+	}
+	files := []string{path}
+	c.root = filepath.Dir(path)
+	if info.IsDir() {
+		c.root = path
+		if files, err = markdownFiles(path); err != nil {
+			fmt.Fprintf(stderr, "incipit: %s\n", err)
+			return 2
+		}
+	}
+	broken, err := c.check(files)
+	if err != nil {
+		fmt.Fprintf(stderr, "incipit: %s\n", err)
+		return 2
+	}
+	for _, b := range broken {
+		fmt.Fprintln(stdout, b)
+	}
+	if len(broken) > 0 {
+		return 1
+	}
+	return 0
+}
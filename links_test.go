@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanLinks(t *testing.T) {
+	md := "---\ntitle: x\n---\nSee [a](a.md#intro \"A\"), ![img](<b c.png>) and <https://x.org>.\n" +
+		"`[no](code.md)`\n\n```\n[no](fence.md)\n```\n[ref]: docs/ \n<a href=\"c.md\">c</a>\n"
+	want := []linkRef{
+		{4, 9, "a.md#intro"},
+		{4, 34, "b c.png"},
+		{4, 49, "https://x.org"},
+		{10, 8, "docs/"},
+		{11, 10, "c.md"},
+	}
+	if got := scanLinks(md); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+// writeDocs creates files under a temporary directory and returns it.
+func writeDocs(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestCheckLinks_Local(t *testing.T) {
+	dir := writeDocs(t, map[string]string{
+		"README.md": "# Intro\n\n[ok](docs/guide.md#set-up) [dir](docs/) [self](#intro) [root](/docs/guide.md)\n" +
+			"[gone](missing.md) [bad](docs/guide.md#nope) [mail](mailto:a@b.c) [web](https://example.invalid)\n",
+		"docs/guide.md": "# Guide\n\n## Set up\n\n[back](../README.md#Intro) [html](#custom)\n\n<a id=\"custom\"></a>\n",
+	})
+	var stdout, stderr bytes.Buffer
+	if code := checkLinks(dir, newLinkChecker(false), &stdout, &stderr); code != 1 {
+		t.Fatalf("expected status 1, got %d %q", code, stderr.String())
+	}
+	readme := filepath.Join(dir, "README.md")
+	want := readme + ":4:8: missing.md: no such file or directory\n" +
+		readme + ":4:26: docs/guide.md#nope: no heading with anchor #nope\n"
+	if stdout.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", stdout.String(), want)
+	}
+}
+
+func TestScanLinks_NestedImage(t *testing.T) {
+	want := []linkRef{{1, 8, "badge.svg"}, {1, 20, "missing.md"}}
+	if got := scanLinks("[![CI](badge.svg)](missing.md)\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckLinks_SlugsWithUnderscoresAndCode(t *testing.T) {
+	dir := writeDocs(t, map[string]string{
+		"doc.md": "# Doc\n\n[b](#snake_case) [c](#max_width-option) [d](#use-links-here) [e](#snakecase)\n\n" +
+			"## snake_case\n\n## `max_width` option\n\n## Use [links](http://x.y) here\n",
+	})
+	path := filepath.Join(dir, "doc.md")
+	var stdout, stderr bytes.Buffer
+	checkLinks(path, newLinkChecker(false), &stdout, &stderr)
+	if want := path + ":3:66: #snakecase: no heading with anchor #snakecase\n"; stdout.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", stdout.String(), want)
+	}
+}
+
+func TestCheckLinks_External(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/head-refused" && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == "/missing":
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	doc := "[a](" + srv.URL + "/ok) [b](" + srv.URL + "/head-refused) <" + srv.URL + "/missing>\n"
+	dir := writeDocs(t, map[string]string{"doc.md": doc})
+	path := filepath.Join(dir, "doc.md")
+
+	var stdout, stderr bytes.Buffer
+	if code := checkLinks(path, newLinkChecker(false), &stdout, &stderr); code != 0 {
+		t.Errorf("expected external links to be skipped, got %d %q", code, stdout.String())
+	}
+	c := newLinkChecker(true)
+	c.client = srv.Client()
+	if code := checkLinks(path, c, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected status 1, got %d %q", code, stderr.String())
+	}
+	if got := strings.TrimSpace(stdout.String()); !strings.HasSuffix(got, "/missing: 404 Not Found") || strings.Count(got, "\n") != 0 {
+		t.Errorf("unexpected report %q", got)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Lint rules, enabled by default and switched off with --disable or the
+// lint-disable config key.
+const (
+	ruleHeadingIncrement   = "heading-increment"
+	ruleDuplicateSlug      = "duplicate-slug"
+	ruleFenceLanguage      = "fence-language"
+	ruleTrailingWhitespace = "trailing-whitespace"
+	ruleBareURL            = "bare-url"
+	ruleListMarker         = "list-marker"
+)
+
+// lintRules lists every lint rule in the order problems are described.
+var lintRules = []string{
+	ruleHeadingIncrement, ruleDuplicateSlug, ruleFenceLanguage,
+	ruleTrailingWhitespace, ruleBareURL, ruleListMarker,
+}
+
+var (
+	// bareURLRe matches a URL that is not part of a link, an autolink or an
+	// HTML attribute. Group 1 = URL.
+	bareURLRe = regexp.MustCompile(`(?:^|[^(<\[\w/"'=])(https?://[^\s<>()\[\]]+)`)
+
+	// linkDefRe matches a link reference definition.
+	linkDefRe = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s`)
+)
+
+// lintProblem is one problem found in a document. Line and Col are 1-based,
+// Col counted in characters.
+type lintProblem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Col     int    `json:"col"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (p lintProblem) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", p.File, p.Line, p.Col, p.Rule, p.Message)
+}
+
+// column returns the 1-based character column of byte offset i in line.
+func column(line string, i int) int {
+	return utf8.RuneCountInString(line[:i]) + 1
+}
+
+// maskCodeSpans returns line with the contents of code spans blanked out, so
+// offsets into the result are offsets into line.
+func maskCodeSpans(line string) string {
+	outside := mapOutsideCodeSpans(line, func(s string) string { return strings.Repeat("\x00", len(s)) })
+	b := []byte(line)
+	for i := range b {
+		if outside[i] != 0 {
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
+
+// lintDocument checks a file's contents against the enabled rules and returns
+// the problems in line order. Front matter is skipped.
+func lintDocument(filename, content string, disabled []string) []lintProblem {
+	on := func(rule string) bool { return !slices.Contains(disabled, rule) }
+	var problems []lintProblem
+	report := func(line, col int, rule, format string, args ...any) {
+		problems = append(problems, lintProblem{filename, line + 1, col, rule, fmt.Sprintf(format, args...)})
+	}
+
+	prevLevel := 0
+	firstSlug := map[string]int{}
+	for _, h := range documentHeadings(content) {
+		if on(ruleHeadingIncrement) && prevLevel > 0 && h.level > prevLevel+1 {
+			report(h.line, 1, ruleHeadingIncrement, "heading level %d after level %d", h.level, prevLevel)
+		}
+		prevLevel = h.level
+		slug := headingSlug(h.text)
+		if first, ok := firstSlug[slug]; ok {
+			if on(ruleDuplicateSlug) {
+				report(h.line, 1, ruleDuplicateSlug, "duplicate heading slug %q (first at line %d)", slug, first+1)
+			}
+		} else {
+			firstSlug[slug] = h.line
+		}
+	}
+
+	body, _ := extractFrontMatter(content)
+	offset := strings.Count(content[:len(content)-len(body)], "\n")
+	lines := strings.Split(body, "\n")
+	fence := ""
+	marker := ""
+	for i, line := range lines {
+		n := i + offset
+		if strings.TrimRight(line, " \t") != line && on(ruleTrailingWhitespace) {
+			trimmed := strings.TrimRight(line, " \t")
+			hardBreak := fence == "" && strings.HasSuffix(line, "  ") && len(line)-len(trimmed) == 2 &&
+				trimmed != "" && i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != ""
+			if !hardBreak {
+				report(n, column(line, len(trimmed)), ruleTrailingWhitespace, "trailing whitespace")
+			}
+		}
+		if sub := fenceRe.FindStringSubmatch(line); sub != nil {
+			if fence == "" {
+				fence = sub[1]
+				info := strings.TrimLeft(strings.TrimSpace(line), sub[1][:1])
+				if strings.TrimSpace(info) == "" && on(ruleFenceLanguage) {
+					report(n, column(line, strings.Index(line, sub[1])), ruleFenceLanguage, "fenced code block without a language")
+				}
+			} else if sub[1] == fence {
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+		if sub := bulletRe.FindStringSubmatchIndex(line); sub != nil && !thematicBreakRe.MatchString(line) {
+			m := line[sub[4]:sub[5]]
+			switch {
+			case marker == "":
+				marker = m
+			case m != marker && on(ruleListMarker):
+				report(n, column(line, sub[4]), ruleListMarker, "list marker %q, document uses %q", m, marker)
+			}
+		}
+		if on(ruleBareURL) && !linkDefRe.MatchString(line) && !strings.HasPrefix(line, "    ") {
+			masked := maskCodeSpans(line)
+			for _, loc := range bareURLRe.FindAllStringSubmatchIndex(masked, -1) {
+				url := strings.TrimRight(line[loc[2]:loc[3]], ".,;:!?")
+				report(n, column(line, loc[2]), ruleBareURL, "bare URL %s, wrap it in <> or a link", url)
+			}
+		}
+	}
+	slices.SortStableFunc(problems, func(a, b lintProblem) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Col - b.Col
+	})
+	return problems
+}
+
+// checkLintRules returns an error naming the first unknown rule in names.
+func checkLintRules(names []string) error {
+	for _, name := range names {
+		if !slices.Contains(lintRules, name) {
+			return fmt.Errorf("unknown lint rule %q (known: %s)", name, strings.Join(lintRules, ", "))
+		}
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// runLint implements "incipit lint": it checks each file and prints the
+// problems found as text or JSON. It returns 1 when there are problems.
+func runLint(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "text", "output format: text or json")
+	disable := fs.String("disable", "", "comma-separated rules to skip: "+strings.Join(lintRules, ", "))
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: incipit lint [--format text|json] [--disable RULE,...] <file.md>...\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || *format != "text" && *format != "json" {
+		fs.Usage()
+		return 2
+	}
+	disabled := splitList(*disable)
+	if err := checkLintRules(disabled); err != nil {
+		fmt.Fprintf(stderr, "incipit: %s\n", err)
+		return 2
+	}
+
+	problems := []lintProblem{}
+	status := 0
+	for _, filename := range fs.Args() {
+		cfg, err := loadConfig(filepath.Dir(filename))
+		var data []byte
+		if err == nil {
+			data, err = os.ReadFile(filename)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "incipit: %s\n", err)
+			status = 2
+			continue
+		}
+		problems = append(problems, lintDocument(filename, string(data), append(cfg.lintDisable, disabled...))...)
+	}
+
+	if *format == "json" {
+		data, _ := json.MarshalIndent(problems, "", "  ")
+		fmt.Fprintf(stdout, "%s\n", data)
+	} else {
+		for _, p := range problems {
+			fmt.Fprintln(stdout, p)
+		}
+	}
+	if status == 0 && len(problems) > 0 {
+		status = 1
+	}
+	return status
+}
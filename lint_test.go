@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintDocument(t *testing.T) {
+	md := "---\ntitle: x\n---\n# A\n\n### B \n\nSee https://example.com, <https://ok.com>, [x](https://ok.com) and `https://code`.\n\n```\ncode\n```\n\n- one\n* two\n\n## B\n"
+	var got []string
+	for _, p := range lintDocument("doc.md", md, nil) {
+		got = append(got, p.String())
+	}
+	want := []string{
+		"doc.md:6:1: heading-increment: heading level 3 after level 1",
+		"doc.md:6:6: trailing-whitespace: trailing whitespace",
+		"doc.md:8:5: bare-url: bare URL https://example.com, wrap it in <> or a link",
+		"doc.md:10:1: fence-language: fenced code block without a language",
+		"doc.md:15:1: list-marker: list marker \"*\", document uses \"-\"",
+		"doc.md:17:1: duplicate-slug: duplicate heading slug \"b\" (first at line 6)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLintDocument_DisabledRulesAndHardBreaks(t *testing.T) {
+	md := "# A\n\n### B\n\nLine with a break  \nnext https://example.com\n"
+	problems := lintDocument("doc.md", md, []string{ruleHeadingIncrement, ruleBareURL})
+	if len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+}
+
+func TestRunLint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "doc.md")
+	if err := os.WriteFile(path, []byte("# A\n\n```\nx\n```\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	if code := runLint([]string{"--format", "json", path}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected status 1, got %d %q", code, stderr.String())
+	}
+	var problems []lintProblem
+	if err := json.Unmarshal(stdout.Bytes(), &problems); err != nil || len(problems) != 1 || problems[0].Rule != ruleFenceLanguage {
+		t.Errorf("unexpected JSON %q (%v)", stdout.String(), err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, configName), []byte("lint-disable: fence-language\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if code := runLint([]string{path}, &stdout, &stderr); code != 0 || stdout.Len() != 0 {
+		t.Errorf("expected the config to disable the rule, got %d %q", code, stdout.String())
+	}
+	if code := runLint([]string{"--disable", "nope", path}, &stdout, &stderr); code != 2 {
+		t.Errorf("expected an unknown rule to be rejected, got %d", code)
+	}
+}
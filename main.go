@@ -4,8 +4,12 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 	"golang.org/x/term"
 )
 
@@ -22,20 +26,102 @@ func chooseStyle(dark, light, noColor bool) string {
 	return "dark"
 }
 
+// renderStatic renders a file's contents for non-interactive output: the
+// front matter card, if any, followed by the document.
+func renderStatic(content, style string, width int, opts renderOptions) string {
+	body, fm := extractFrontMatter(content)
+	out := renderDocument(body, style, width, opts)
+	if card := renderFrontMatter(fm, width, style); card != "" {
+		out = card + "\n" + out
+	}
+	return out
+}
+
+// renderANSI is renderStatic with colors, whether or not stdout is a
+// terminal. Anything but "light" gets the dark theme.
+func renderANSI(content, style string, width int, opts renderOptions) string {
+	if style != "light" {
+		style = "dark"
+	}
+	profile := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	defer lipgloss.SetColorProfile(profile)
+	return renderStatic(content, style, width, opts)
+}
+
+// renderPlain is renderStatic without escape sequences or trailing padding.
+// It strips a themed rendering rather than using the notty style, which keeps
+// emphasis markers.
+func renderPlain(content string, width int, opts renderOptions) string {
+	lines := strings.Split(stripANSI(renderStatic(content, "dark", width, opts)), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "fmt" {
+		os.Exit(runFmt(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		os.Exit(runLint(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "check-links" {
+		os.Exit(runCheckLinks(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "grep" {
+		os.Exit(runGrep(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	var (
-		darkFlag    bool
-		lightFlag   bool
-		noPagerFlag bool
-		noColorFlag bool
+		darkFlag      bool
+		lightFlag     bool
+		noPagerFlag   bool
+		noColorFlag   bool
+		tasksFlag     bool
+		numberFlag    bool
+		anchorsFlag   bool
+		sectionFlag   string
+		tocFlag       bool
+		tocFormatFlag string
+		tocLinesFlag  bool
+		tocSlugsFlag  bool
+		updateTOCFlag bool
+		widthFlag     int
+		maxWidthFlag  int
+		formatFlag    string
+		chromeFlag    bool
+		noBordersFlag bool
 	)
 
 	flag.BoolVar(&darkFlag, "dark", false, "force dark color theme (default)")
 	flag.BoolVar(&lightFlag, "light", false, "force light color theme")
 	flag.BoolVar(&noPagerFlag, "no-pager", false, "print rendered output without interactive pager")
 	flag.BoolVar(&noColorFlag, "no-color", false, "disable ANSI colors")
+	flag.BoolVar(&tasksFlag, "tasks", false, "print open task list items with their section path")
+	flag.BoolVar(&numberFlag, "number-headings", false, "number headings hierarchically (1, 1.2, 1.2.3)")
+	flag.BoolVar(&anchorsFlag, "show-anchors", false, "show each heading's GitHub anchor slug")
+	flag.StringVar(&sectionFlag, "section", "", "show only the named section, e.g. \"Usage/Options\" or a slug")
+	flag.StringVar(&sectionFlag, "heading", "", "alias for --section")
+	flag.IntVar(&widthFlag, "width", 0, "render width in columns (default: COLUMNS or the terminal width)")
+	flag.IntVar(&maxWidthFlag, "max-width", 0, "reading width: cap the text measure and center it in the pager")
+	flag.StringVar(&formatFlag, "format", "", "output format instead of the pager: ansi, plain, markdown, html or svg")
+	flag.BoolVar(&noBordersFlag, "no-borders", false, "draw code blocks without frames")
+	flag.BoolVar(&chromeFlag, "svg-chrome", false, "draw a terminal window frame around --format svg output")
+	flag.BoolVar(&tocFlag, "toc", false, "print the document outline")
+	flag.StringVar(&tocFormatFlag, "toc-format", "text", "outline format: text, markdown or json")
+	flag.BoolVar(&tocLinesFlag, "toc-lines", false, "show source line numbers in the text outline")
+	flag.BoolVar(&tocSlugsFlag, "toc-slugs", false, "show anchor slugs in the text outline")
+	flag.BoolVar(&updateTOCFlag, "update-toc", false, "rewrite the <!-- toc --> ... <!-- tocstop --> region in place")
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: incipit [--dark|--light] [--no-pager] [--no-color] <file.md>\n")
+		fmt.Fprintf(os.Stderr, "Usage: incipit [--dark|--light] [--no-pager] [--no-color] [--tasks] [--number-headings] [--show-anchors] [--section NAME] [--width N] [--max-width N] [--format ansi|plain|markdown|html|svg] [--no-borders] [--svg-chrome] <file.md|dir>\n")
+		fmt.Fprintf(os.Stderr, "       incipit --toc [--toc-format text|markdown|json] [--toc-lines] [--toc-slugs] [--section NAME] <file.md>\n")
+		fmt.Fprintf(os.Stderr, "       incipit --update-toc <file.md>\n")
+		fmt.Fprintf(os.Stderr, "       incipit fmt [--write|--check] [--wrap N] <file.md>...\n")
+		fmt.Fprintf(os.Stderr, "       incipit lint [--format text|json] [--disable RULE,...] <file.md>...\n")
+		fmt.Fprintf(os.Stderr, "       incipit check-links [--external] <file.md|dir>\n")
+		fmt.Fprintf(os.Stderr, "       incipit grep [-i] [-F] [-C N] PATTERN [dir]\n")
 	}
 	flag.Parse()
 
@@ -43,6 +129,10 @@ func main() {
 		fmt.Fprintf(os.Stderr, "incipit: --dark and --light are mutually exclusive\n")
 		os.Exit(1)
 	}
+	if widthFlag < 0 || maxWidthFlag < 0 {
+		fmt.Fprintf(os.Stderr, "incipit: --width and --max-width must be positive\n")
+		os.Exit(1)
+	}
 
 	args := flag.Args()
 	if len(args) != 1 {
@@ -51,23 +141,145 @@ func main() {
 	}
 
 	filename := args[0]
+	style := chooseStyle(darkFlag, lightFlag, noColorFlag)
+	isTTY := term.IsTerminal(int(os.Stdout.Fd()))
+	settings := pagerSettings{
+		width:    widthFlag,
+		maxWidth: maxWidthFlag,
+		opts: renderOptions{
+			noBorders:      noBordersFlag,
+			numberHeadings: numberFlag,
+			showAnchors:    anchorsFlag,
+		},
+	}
+	if isTTY {
+		settings.opts.graphics = detectGraphics()
+	}
+
+	// A directory opens the file browser, or lists its documents when there
+	// is no terminal to browse in.
+	if info, err := os.Stat(filename); err == nil && info.IsDir() && !updateTOCFlag {
+		files, err := markdownFiles(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "incipit: %s\n", err)
+			os.Exit(1)
+		}
+		if noPagerFlag || !isTTY {
+			for _, f := range files {
+				fmt.Println(f)
+			}
+			return
+		}
+		p := tea.NewProgram(newBrowser(filename, files, style, settings), tea.WithAltScreen(), tea.WithMouseCellMotion())
+		if _, err := p.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "incipit: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if updateTOCFlag {
+		if err := updateTOCFile(filename); err != nil {
+			fmt.Fprintf(os.Stderr, "incipit: %s: %s\n", filename, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "incipit: %s\n", err)
 		os.Exit(1)
 	}
 
-	style := chooseStyle(darkFlag, lightFlag, noColorFlag)
 	content := string(data)
 
+	if tocFlag {
+		out, err := formatTOC(content, tocOptions{format: tocFormatFlag, lines: tocLinesFlag, slugs: tocSlugsFlag, section: sectionFlag})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "incipit: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(out)
+		return
+	}
+
+	if sectionFlag != "" {
+		body, _ := extractFrontMatter(content)
+		section, ok := findSection(body, sectionFlag)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "incipit: %s\n", sectionNotFound(body, sectionFlag))
+			os.Exit(1)
+		}
+		content = section
+	}
+
+	if tasksFlag {
+		body, _ := extractFrontMatter(content)
+		fmt.Print(formatOpenTasks(body))
+		return
+	}
+
 	// Non-interactive mode: --no-pager flag or stdout is not a TTY
-	if noPagerFlag || !term.IsTerminal(int(os.Stdout.Fd())) {
-		out := renderMarkdown(content, style, 80)
+	opts := renderOptions{
+		baseDir:        filepath.Dir(filename),
+		noBorders:      noBordersFlag,
+		numberHeadings: numberFlag,
+		showAnchors:    anchorsFlag,
+	}
+	cfg, err := loadConfig(opts.baseDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "incipit: %s\n", err)
+		os.Exit(1)
+	}
+	cfg.apply(&opts)
+	maxWidth := cfg.maxWidth
+	if maxWidthFlag > 0 {
+		maxWidth = maxWidthFlag
+	}
+	width := resolveWidth(widthFlag, int(os.Stdout.Fd()))
+	if maxWidth > 0 {
+		width = min(width, maxWidth)
+	}
+	switch formatFlag {
+	case "":
+	case "ansi":
+		fmt.Print(renderANSI(content, style, width, opts))
+		return
+	case "plain":
+		fmt.Print(renderPlain(content, width, opts))
+		return
+	case "markdown", "md":
+		fmt.Print(formatMarkdown(content))
+		return
+	case "html":
+		out, err := exportHTML(content, filename, style, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "incipit: %s\n", err)
+			os.Exit(1)
+		}
 		fmt.Print(out)
 		return
+	case "svg":
+		fmt.Print(exportSVG(content, style, width, opts, svgOptions{title: filepath.Base(filename), chrome: chromeFlag}))
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "incipit: unknown format %q (known: ansi, plain, markdown, html, svg)\n", formatFlag)
+		os.Exit(1)
 	}
 
-	m := newModel(filename, content, style)
+	if noPagerFlag || !isTTY {
+		opts.graphics = settings.opts.graphics
+		fmt.Print(renderStatic(content, style, width, opts))
+		return
+	}
+
+	m, err := settings.newPager(filename, content, style)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "incipit: %s\n", err)
+		os.Exit(1)
+	}
+	m.excerpt = sectionFlag != ""
 	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
 	if _, err := p.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "incipit: %s\n", err)
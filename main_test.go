@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderPlain(t *testing.T) {
+	md := "---\ntitle: Doc\n---\n# Title\n\nSome **bold** text.\n\n```go\nx := 1\n```\n"
+	out := renderPlain(md, 60, renderOptions{noBorders: true})
+	if strings.Contains(out, "\x1b") {
+		t.Errorf("expected no escape sequences, got %q", out)
+	}
+	for _, want := range []string{"title  Doc", "Title", "Some bold text.", "    x := 1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got %q", want, out)
+		}
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasSuffix(line, " ") {
+			t.Errorf("expected no trailing padding, got %q", line)
+		}
+	}
+}
+
+func TestRenderANSI_ForcesColor(t *testing.T) {
+	out := renderANSI("# Title\n", "notty", 40, renderOptions{})
+	_, bg, _ := headerColors(1, "dark")
+	if !strings.Contains(out, "48;5;"+bg) {
+		t.Errorf("expected heading colors in ANSI output, got %q", out)
+	}
+}
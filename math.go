@@ -0,0 +1,601 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// mathPlaceholderRe matches an INCIPIT_MATH_N placeholder. Group 1 = index.
+var mathPlaceholderRe = regexp.MustCompile(`INCIPIT_MATH_(\d+)`)
+
+// texSymbols maps TeX commands that stand for a single symbol.
+var texSymbols = map[string]string{
+	// Greek
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ", "varepsilon": "ε",
+	"zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ", "iota": "ι", "kappa": "κ",
+	"lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ", "pi": "π", "varpi": "ϖ", "rho": "ρ",
+	"varrho": "ϱ", "sigma": "σ", "varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "ϕ",
+	"varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ", "Pi": "Π",
+	"Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+	// Big operators
+	"sum": "∑", "prod": "∏", "coprod": "∐", "int": "∫", "iint": "∬", "iiint": "∭", "oint": "∮",
+	"bigcup": "⋃", "bigcap": "⋂",
+	// Binary operators and relations
+	"times": "×", "cdot": "·", "div": "÷", "pm": "±", "mp": "∓", "ast": "∗", "star": "⋆",
+	"circ": "∘", "bullet": "•", "oplus": "⊕", "otimes": "⊗", "cup": "∪", "cap": "∩",
+	"wedge": "∧", "land": "∧", "vee": "∨", "lor": "∨", "neg": "¬", "lnot": "¬", "setminus": "∖",
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠", "approx": "≈",
+	"equiv": "≡", "sim": "∼", "simeq": "≃", "cong": "≅", "propto": "∝", "ll": "≪", "gg": "≫",
+	"in": "∈", "notin": "∉", "ni": "∋", "subset": "⊂", "supset": "⊃", "subseteq": "⊆",
+	"supseteq": "⊇", "perp": "⊥", "parallel": "∥", "mid": "∣",
+	// Arrows
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "gets": "←", "leftrightarrow": "↔",
+	"Rightarrow": "⇒", "Leftarrow": "⇐", "Leftrightarrow": "⇔", "implies": "⟹", "iff": "⟺",
+	"mapsto": "↦", "uparrow": "↑", "downarrow": "↓", "longrightarrow": "⟶", "longleftarrow": "⟵",
+	// Misc symbols
+	"infty": "∞", "partial": "∂", "nabla": "∇", "forall": "∀", "exists": "∃", "nexists": "∄",
+	"emptyset": "∅", "varnothing": "∅", "ldots": "…", "dots": "…", "cdots": "⋯", "vdots": "⋮",
+	"ddots": "⋱", "prime": "′", "angle": "∠", "triangle": "△", "hbar": "ℏ", "ell": "ℓ",
+	"Re": "ℜ", "Im": "ℑ", "aleph": "ℵ", "degree": "°", "therefore": "∴", "because": "∵",
+	// Delimiters
+	"langle": "⟨", "rangle": "⟩", "lfloor": "⌊", "rfloor": "⌋", "lceil": "⌈", "rceil": "⌉",
+	"vert": "|", "Vert": "‖", "lvert": "|", "rvert": "|", "lVert": "‖", "rVert": "‖",
+	// Spacing
+	"quad": "  ", "qquad": "    ",
+}
+
+// texFunctions are operator names typeset upright, e.g. \sin → "sin".
+var texFunctions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "cot": true, "sec": true, "csc": true,
+	"arcsin": true, "arccos": true, "arctan": true, "sinh": true, "cosh": true, "tanh": true,
+	"log": true, "ln": true, "lg": true, "exp": true, "lim": true, "liminf": true, "limsup": true,
+	"max": true, "min": true, "sup": true, "inf": true, "det": true, "dim": true, "ker": true,
+	"deg": true, "gcd": true, "arg": true, "Pr": true, "mod": true, "bmod": true,
+}
+
+// texAccents maps accent commands to the combining character they add.
+var texAccents = map[string]string{
+	"hat": "̂", "widehat": "̂", "bar": "̄", "overline": "̅",
+	"vec": "⃗", "dot": "̇", "ddot": "̈", "tilde": "̃",
+	"widetilde": "̃", "underline": "̲",
+}
+
+// texBlackboard maps \mathbb letters to their double-struck forms.
+var texBlackboard = map[rune]string{
+	'N': "ℕ", 'Z': "ℤ", 'Q': "ℚ", 'R': "ℝ", 'C': "ℂ", 'P': "ℙ", 'H': "ℍ", 'E': "𝔼", '1': "𝟙",
+}
+
+var superscripts = map[rune]rune{
+	'0': '⁰', '1': '¹', '2': '²', '3': '³', '4': '⁴', '5': '⁵', '6': '⁶', '7': '⁷', '8': '⁸', '9': '⁹',
+	'+': '⁺', '-': '⁻', '−': '⁻', '=': '⁼', '(': '⁽', ')': '⁾',
+	'a': 'ᵃ', 'b': 'ᵇ', 'c': 'ᶜ', 'd': 'ᵈ', 'e': 'ᵉ', 'f': 'ᶠ', 'g': 'ᵍ', 'h': 'ʰ', 'i': 'ⁱ',
+	'j': 'ʲ', 'k': 'ᵏ', 'l': 'ˡ', 'm': 'ᵐ', 'n': 'ⁿ', 'o': 'ᵒ', 'p': 'ᵖ', 'r': 'ʳ', 's': 'ˢ',
+	't': 'ᵗ', 'u': 'ᵘ', 'v': 'ᵛ', 'w': 'ʷ', 'x': 'ˣ', 'y': 'ʸ', 'z': 'ᶻ',
+	'A': 'ᴬ', 'B': 'ᴮ', 'D': 'ᴰ', 'E': 'ᴱ', 'G': 'ᴳ', 'H': 'ᴴ', 'I': 'ᴵ', 'J': 'ᴶ', 'K': 'ᴷ',
+	'L': 'ᴸ', 'M': 'ᴹ', 'N': 'ᴺ', 'O': 'ᴼ', 'P': 'ᴾ', 'R': 'ᴿ', 'T': 'ᵀ', 'U': 'ᵁ', 'V': 'ⱽ', 'W': 'ᵂ',
+	'α': 'ᵅ', 'β': 'ᵝ', 'γ': 'ᵞ', 'δ': 'ᵟ', 'ε': 'ᵋ', 'θ': 'ᶿ', 'ι': 'ᶥ', 'φ': 'ᵠ', 'χ': 'ᵡ',
+	'′': '′', '*': '*', ' ': ' ',
+}
+
+var subscripts = map[rune]rune{
+	'0': '₀', '1': '₁', '2': '₂', '3': '₃', '4': '₄', '5': '₅', '6': '₆', '7': '₇', '8': '₈', '9': '₉',
+	'+': '₊', '-': '₋', '−': '₋', '=': '₌', '(': '₍', ')': '₎',
+	'a': 'ₐ', 'e': 'ₑ', 'h': 'ₕ', 'i': 'ᵢ', 'j': 'ⱼ', 'k': 'ₖ', 'l': 'ₗ', 'm': 'ₘ', 'n': 'ₙ',
+	'o': 'ₒ', 'p': 'ₚ', 'r': 'ᵣ', 's': 'ₛ', 't': 'ₜ', 'u': 'ᵤ', 'v': 'ᵥ', 'x': 'ₓ',
+	'β': 'ᵦ', 'γ': 'ᵧ', 'ρ': 'ᵨ', 'φ': 'ᵩ', 'χ': 'ᵪ', ' ': ' ',
+}
+
+// texParser converts a TeX math string to Unicode text. It handles the common
+// subset of LaTeX used in docs and fails on anything else, so callers can fall
+// back to showing the source.
+type texParser struct {
+	src []rune
+	pos int
+}
+
+// texToUnicode converts TeX math to readable Unicode. It reports false when the
+// input uses a construct the converter does not understand.
+func texToUnicode(tex string) (string, bool) {
+	p := &texParser{src: []rune(tex)}
+	out, err := p.expr(0)
+	if err != nil || p.pos < len(p.src) {
+		return "", false
+	}
+	var lines []string
+	for _, l := range strings.Split(out, "\n") {
+		if l = strings.Join(strings.Fields(l), " "); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return strings.Join(lines, "\n"), len(lines) > 0
+}
+
+// expr parses until end of input or the closing rune stop (not consumed).
+func (p *texParser) expr(stop rune) (string, error) {
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		if stop != 0 && r == stop {
+			return b.String(), nil
+		}
+		switch r {
+		case '^', '_':
+			p.pos++
+			arg, err := p.arg()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(script(arg, r == '^'))
+		case '{':
+			p.pos++
+			inner, err := p.expr('}')
+			if err != nil {
+				return "", err
+			}
+			if p.pos >= len(p.src) {
+				return "", fmt.Errorf("unbalanced {")
+			}
+			p.pos++
+			b.WriteString(inner)
+		case '}':
+			return "", fmt.Errorf("unbalanced }")
+		case '\\':
+			s, err := p.command()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+		case '\'':
+			p.pos++
+			b.WriteString("′")
+		case '&':
+			p.pos++
+			b.WriteString(" ")
+		case '-':
+			p.pos++
+			b.WriteString("−")
+		case '~':
+			p.pos++
+			b.WriteString(" ")
+		default:
+			p.pos++
+			if unicode.IsSpace(r) {
+				b.WriteString(" ")
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	if stop != 0 {
+		return "", fmt.Errorf("missing %q", stop)
+	}
+	return b.String(), nil
+}
+
+// arg parses a single argument: a {group}, a command, or one rune.
+func (p *texParser) arg() (string, error) {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		return "", fmt.Errorf("missing argument")
+	}
+	switch r := p.src[p.pos]; r {
+	case '{':
+		p.pos++
+		s, err := p.expr('}')
+		if err != nil {
+			return "", err
+		}
+		p.pos++
+		return s, nil
+	case '\\':
+		return p.command()
+	case '}', '^', '_':
+		return "", fmt.Errorf("unexpected %q", r)
+	default:
+		p.pos++
+		if r == '-' {
+			return "−", nil
+		}
+		return string(r), nil
+	}
+}
+
+// rawArg returns the source text of a {group} argument without converting it.
+func (p *texParser) rawArg() (string, error) {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+	if p.pos >= len(p.src) || p.src[p.pos] != '{' {
+		return "", fmt.Errorf("expected {")
+	}
+	depth := 0
+	start := p.pos + 1
+	for ; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				p.pos++
+				return string(p.src[start : p.pos-1]), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unbalanced {")
+}
+
+func (p *texParser) command() (string, error) {
+	p.pos++ // backslash
+	if p.pos >= len(p.src) {
+		return "", fmt.Errorf("trailing backslash")
+	}
+	start := p.pos
+	for p.pos < len(p.src) && unicode.IsLetter(p.src[p.pos]) && p.src[p.pos] < unicode.MaxASCII {
+		p.pos++
+	}
+	if p.pos == start {
+		// Control symbol such as \{ or \,
+		r := p.src[p.pos]
+		p.pos++
+		switch r {
+		case ',', ':', ';', ' ':
+			return " ", nil
+		case '!':
+			return "", nil
+		case '\\':
+			return "\n", nil
+		case '|':
+			return "‖", nil
+		case '{', '}', '$', '%', '#', '&', '_':
+			return string(r), nil
+		}
+		return "", fmt.Errorf("unknown control symbol \\%c", r)
+	}
+	name := string(p.src[start:p.pos])
+
+	if s, ok := texSymbols[name]; ok {
+		return s, nil
+	}
+	if texFunctions[name] {
+		return name, nil
+	}
+	if comb, ok := texAccents[name]; ok {
+		a, err := p.arg()
+		if err != nil {
+			return "", err
+		}
+		var b strings.Builder
+		for _, r := range a {
+			b.WriteRune(r)
+			b.WriteString(comb)
+		}
+		return b.String(), nil
+	}
+
+	switch name {
+	case "frac", "dfrac", "tfrac":
+		num, err := p.arg()
+		if err != nil {
+			return "", err
+		}
+		den, err := p.arg()
+		if err != nil {
+			return "", err
+		}
+		return group(num) + "/" + group(den), nil
+	case "sqrt":
+		index := ""
+		if p.pos < len(p.src) && p.src[p.pos] == '[' {
+			p.pos++
+			var err error
+			if index, err = p.expr(']'); err != nil {
+				return "", err
+			}
+			p.pos++
+		}
+		a, err := p.arg()
+		if err != nil {
+			return "", err
+		}
+		root := "√"
+		switch index {
+		case "":
+		case "3":
+			root = "∛"
+		case "4":
+			root = "∜"
+		default:
+			root = script(index, true) + "√"
+		}
+		return root + group(a), nil
+	case "text", "textrm", "textit", "textbf", "mathrm", "mathit", "mathbf", "mathsf", "mathtt", "operatorname", "boldsymbol":
+		a, err := p.rawArg()
+		if err != nil {
+			return "", err
+		}
+		return a, nil
+	case "mathbb":
+		a, err := p.rawArg()
+		if err != nil {
+			return "", err
+		}
+		var b strings.Builder
+		for _, r := range a {
+			if s, ok := texBlackboard[r]; ok {
+				b.WriteString(s)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+		return b.String(), nil
+	case "left", "right", "bigl", "bigr", "Bigl", "Bigr", "big", "Big":
+		// Sizing prefixes: keep the delimiter that follows.
+		if p.pos < len(p.src) && p.src[p.pos] == '.' {
+			p.pos++
+			return "", nil
+		}
+		return p.arg()
+	case "displaystyle", "textstyle", "limits", "nolimits":
+		return "", nil
+	case "begin", "end":
+		env, err := p.rawArg()
+		if err != nil {
+			return "", err
+		}
+		switch strings.TrimSuffix(env, "*") {
+		case "aligned", "align", "gathered", "gather", "split", "equation":
+			return "", nil
+		}
+		return "", fmt.Errorf("unsupported environment %q", env)
+	}
+	return "", fmt.Errorf("unknown command \\%s", name)
+}
+
+// script converts s to superscript or subscript runes, falling back to ^(s)
+// or _(s) when some rune has no Unicode form.
+func script(s string, sup bool) string {
+	table, mark := subscripts, "_"
+	if sup {
+		table, mark = superscripts, "^"
+	}
+	var b strings.Builder
+	for _, r := range s {
+		m, ok := table[r]
+		if !ok {
+			return mark + group(s)
+		}
+		b.WriteRune(m)
+	}
+	return b.String()
+}
+
+// group wraps s in parentheses unless it is a single symbol or number.
+func group(s string) string {
+	s = strings.TrimSpace(s)
+	if len([]rune(s)) <= 1 {
+		return s
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return s
+	}
+	atomic := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r) {
+			atomic = false
+			break
+		}
+	}
+	if atomic || strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		return s
+	}
+	return "(" + s + ")"
+}
+
+// mathEscaper escapes converted math so glamour prints it literally.
+var mathEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`,
+	"`", "\\`", "|", `\|`, "~", `\~`, "#", `\#`,
+)
+
+// replaceInlineMath converts $...$ and inline $$...$$ spans in a line of
+// markdown. Converted math is escaped for markdown when escape is set; math
+// the converter cannot handle becomes an inline code span of its source.
+// Dollars inside code spans, escaped dollars, and currency-like text such as
+// "$5 and $10" are left alone.
+func replaceInlineMath(line string, escape bool) string {
+	if !strings.Contains(line, "$") {
+		return line
+	}
+	rs := []rune(line)
+	var b strings.Builder
+	for i := 0; i < len(rs); {
+		switch {
+		case rs[i] == '\\' && i+1 < len(rs):
+			b.WriteRune(rs[i])
+			b.WriteRune(rs[i+1])
+			i += 2
+			continue
+		case rs[i] == '`':
+			// Copy a code span verbatim.
+			n := 0
+			for i+n < len(rs) && rs[i+n] == '`' {
+				n++
+			}
+			fence := strings.Repeat("`", n)
+			if end := strings.Index(string(rs[i+n:]), fence); end >= 0 {
+				endRunes := len([]rune(string(rs[i+n:])[:end]))
+				b.WriteString(string(rs[i : i+n+endRunes+n]))
+				i += n + endRunes + n
+				continue
+			}
+			b.WriteString(fence)
+			i += n
+			continue
+		case rs[i] == '$':
+			delim := 1
+			if i+1 < len(rs) && rs[i+1] == '$' {
+				delim = 2
+			}
+			if end := closingDollar(rs, i+delim, delim); end >= 0 {
+				tex := string(rs[i+delim : end])
+				b.WriteString(inlineMath(tex, escape))
+				i = end + delim
+				continue
+			}
+		}
+		b.WriteRune(rs[i])
+		i++
+	}
+	return b.String()
+}
+
+// closingDollar finds the closing delimiter for math opened just before
+// start. Inline math must not start or end with a space, must not run into a
+// code span, and a closing single dollar must not be followed by a digit.
+func closingDollar(rs []rune, start, delim int) int {
+	if start >= len(rs) || (delim == 1 && unicode.IsSpace(rs[start])) {
+		return -1
+	}
+	for j := start; j < len(rs); j++ {
+		if rs[j] == '\\' {
+			j++
+			continue
+		}
+		if rs[j] == '`' {
+			return -1
+		}
+		if rs[j] != '$' {
+			continue
+		}
+		if delim == 2 {
+			if j+1 < len(rs) && rs[j+1] == '$' && j > start {
+				return j
+			}
+			continue
+		}
+		if j == start || unicode.IsSpace(rs[j-1]) {
+			return -1
+		}
+		if j+1 < len(rs) && unicode.IsDigit(rs[j+1]) {
+			return -1
+		}
+		return j
+	}
+	return -1
+}
+
+func inlineMath(tex string, escape bool) string {
+	out, ok := texToUnicode(tex)
+	if ok && !strings.Contains(out, "\n") {
+		if escape {
+			return mathEscaper.Replace(out)
+		}
+		return out
+	}
+	if !escape {
+		return tex
+	}
+	return "`" + tex + "`"
+}
+
+// extractMath pulls display math ($$ on its own lines) out of md, replacing
+// each block with a unique placeholder paragraph, and converts inline math in
+// the remaining prose. It returns the modified prose plus the display blocks.
+func extractMath(md string) (string, []string) {
+	var blocks []string
+	lines := strings.Split(md, "\n")
+	var out []string
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, "$$") {
+			rest := strings.TrimPrefix(trimmed, "$$")
+			var tex []string
+			closed := false
+			if strings.HasSuffix(rest, "$$") {
+				tex = append(tex, strings.TrimSuffix(rest, "$$"))
+				closed = true
+			} else {
+				if rest != "" {
+					tex = append(tex, rest)
+				}
+				for j := i + 1; j < len(lines); j++ {
+					l := strings.TrimSpace(lines[j])
+					if strings.HasSuffix(l, "$$") {
+						if l = strings.TrimSuffix(l, "$$"); l != "" {
+							tex = append(tex, l)
+						}
+						i = j
+						closed = true
+						break
+					}
+					tex = append(tex, lines[j])
+				}
+			}
+			if closed {
+				out = append(out, fmt.Sprintf("\nINCIPIT_MATH_%d\n", len(blocks)))
+				blocks = append(blocks, strings.Join(tex, "\n"))
+				continue
+			}
+		}
+		out = append(out, replaceInlineMath(lines[i], true))
+	}
+	return strings.Join(out, "\n"), blocks
+}
+
+// renderDisplayMath renders a display math block centred in width. Source the
+// converter cannot handle is shown verbatim, styled like inline code.
+func renderDisplayMath(tex string, width int, style string) string {
+	var lines []string
+	if out, ok := texToUnicode(tex); ok {
+		lines = strings.Split(out, "\n")
+	} else {
+		s := lipgloss.NewStyle()
+		if style != "notty" {
+			s = s.Foreground(lipgloss.Color("203")).Background(lipgloss.Color(inlineCodeBg(style)))
+		}
+		for _, l := range strings.Split(strings.TrimSpace(tex), "\n") {
+			lines = append(lines, s.Render(" "+strings.TrimSpace(l)+" "))
+		}
+	}
+	for i, l := range lines {
+		if pad := (width - lipgloss.Width(l)) / 2; pad > 0 {
+			lines[i] = strings.Repeat(" ", pad) + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inlineCodeBg returns the 256-color background glamour uses for inline code.
+func inlineCodeBg(style string) string {
+	if style == "light" {
+		return "254"
+	}
+	return "236"
+}
+
+// injectMath replaces INCIPIT_MATH_N placeholder lines in rendered with the
+// centred display math for each corresponding block.
+func injectMath(rendered string, blocks []string, width int, style string) string {
+	lines := strings.Split(rendered, "\n")
+	for i, line := range lines {
+		sub := mathPlaceholderRe.FindStringSubmatch(stripANSI(line))
+		if sub == nil {
+			continue
+		}
+		n, err := strconv.Atoi(sub[1])
+		if err != nil || n >= len(blocks) {
+			continue
+		}
+		lines[i] = renderDisplayMath(blocks[n], width, style)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// texToUnicode tests
+
+func TestTexToUnicode_Constructs(t *testing.T) {
+	cases := []struct {
+		tex  string
+		want string
+	}{
+		{`\alpha + \beta = \gamma`, "α + β = γ"},
+		{`x^2 + y_i`, "x² + yᵢ"},
+		{`e^{-x}`, "e⁻ˣ"},
+		{`\frac{a+b}{2}`, "(a+b)/2"},
+		{`\frac{1}{n}`, "1/n"},
+		{`\sqrt{x^2+y^2}`, "√(x²+y²)"},
+		{`\sqrt[3]{8}`, "∛8"},
+		{`\sum_{i=1}^{n} i`, "∑ᵢ₌₁ⁿ i"},
+		{`\int_0^1 f(x)\,dx`, "∫₀¹ f(x) dx"},
+		{`a \leq b \neq c \times d`, "a ≤ b ≠ c × d"},
+		{`x \in \mathbb{R}`, "x ∈ ℝ"},
+		{`\text{if } x > 0`, "if x > 0"},
+		{`\left( \frac{a}{b} \right)`, "( a/b )"},
+	}
+	for _, tc := range cases {
+		got, ok := texToUnicode(tc.tex)
+		if !ok {
+			t.Errorf("texToUnicode(%q) failed", tc.tex)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("texToUnicode(%q) = %q, want %q", tc.tex, got, tc.want)
+		}
+	}
+}
+
+func TestTexToUnicode_ScriptFallback(t *testing.T) {
+	got, ok := texToUnicode(`x^{q}`)
+	if !ok {
+		t.Fatal("expected conversion to succeed")
+	}
+	if got != "x^q" {
+		t.Errorf("expected ^ fallback for rune without superscript, got %q", got)
+	}
+}
+
+func TestTexToUnicode_Unsupported(t *testing.T) {
+	for _, tex := range []string{`\unknowncmd{x}`, `\begin{pmatrix}1\end{pmatrix}`, `\frac{a}{`, `x^`} {
+		if _, ok := texToUnicode(tex); ok {
+			t.Errorf("expected %q to be unsupported", tex)
+		}
+	}
+}
+
+// replaceInlineMath tests
+
+func TestReplaceInlineMath_Converts(t *testing.T) {
+	got := replaceInlineMath("Energy $E = mc^2$ here.", true)
+	if got != "Energy E = mc² here." {
+		t.Errorf("unexpected conversion: %q", got)
+	}
+}
+
+func TestReplaceInlineMath_LeavesCurrencyAndCode(t *testing.T) {
+	for _, line := range []string{
+		"It costs $5 and $10.",
+		"Use `$HOME/bin` and `$x$` literally.",
+		`Escaped \$x\$ stays.`,
+	} {
+		if got := replaceInlineMath(line, true); got != line {
+			t.Errorf("expected %q unchanged, got %q", line, got)
+		}
+	}
+}
+
+func TestReplaceInlineMath_FallbackToCodeSpan(t *testing.T) {
+	got := replaceInlineMath(`See $\weird{x}$.`, true)
+	if got != "See `\\weird{x}`." {
+		t.Errorf("expected inline code fallback, got %q", got)
+	}
+}
+
+func TestReplaceInlineMath_EscapesMarkdown(t *testing.T) {
+	got := replaceInlineMath(`$a * b$`, true)
+	if got != `a \* b` {
+		t.Errorf("expected markdown-escaped output, got %q", got)
+	}
+}
+
+// extractMath tests
+
+func TestExtractMath_DisplayBlock(t *testing.T) {
+	md := "Before\n\n$$\n\\sum_{k=1}^{n} k\n$$\n\nAfter $x$"
+	prose, blocks := extractMath(md)
+	if len(blocks) != 1 {
+		t.Fatalf("expected 1 display block, got %d", len(blocks))
+	}
+	if blocks[0] != `\sum_{k=1}^{n} k` {
+		t.Errorf("unexpected block source: %q", blocks[0])
+	}
+	if !strings.Contains(prose, "INCIPIT_MATH_0") {
+		t.Errorf("expected placeholder in prose, got %q", prose)
+	}
+	if strings.Contains(prose, "$") {
+		t.Errorf("expected inline math converted too, got %q", prose)
+	}
+}
+
+func TestExtractMath_SingleLineDisplay(t *testing.T) {
+	_, blocks := extractMath("$$ a^2 + b^2 = c^2 $$\n")
+	if len(blocks) != 1 || strings.TrimSpace(blocks[0]) != "a^2 + b^2 = c^2" {
+		t.Errorf("unexpected blocks: %q", blocks)
+	}
+}
+
+// renderDisplayMath tests
+
+func TestRenderDisplayMath_Centered(t *testing.T) {
+	out := renderDisplayMath(`a^2`, 41, "dark")
+	if strings.TrimSpace(out) != "a²" {
+		t.Errorf("expected converted math, got %q", out)
+	}
+	if !strings.HasPrefix(out, strings.Repeat(" ", 19)+"a²") {
+		t.Errorf("expected math centred in 41 columns, got %q", out)
+	}
+}
+
+func TestRenderDisplayMath_FallbackStyledSource(t *testing.T) {
+	out := renderDisplayMath(`\begin{pmatrix}1\end{pmatrix}`, 60, "dark")
+	if !strings.Contains(stripANSI(out), ` \begin{pmatrix}1\end{pmatrix} `) {
+		t.Errorf("expected padded TeX source in fallback, got %q", stripANSI(out))
+	}
+}
+
+// End-to-end tests
+
+func TestRenderMarkdown_Math_EndToEnd(t *testing.T) {
+	md := "Inline $\\pi r^2$.\n\n```math\n\\oint \\nabla\n```\n\n## Area $\\pi$"
+	out := stripANSI(renderMarkdown(md, "dark", 60))
+	for _, want := range []string{"π r²", "∮ ∇", "Area π"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got %q", want, out)
+		}
+	}
+	if strings.Contains(out, "INCIPIT_MATH") || strings.Contains(out, "╭") {
+		t.Error("expected math fence rendered as display math, not a code block")
+	}
+}
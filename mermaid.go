@@ -0,0 +1,1034 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Mermaid diagrams are drawn on a character canvas. Connector lines are kept as
+// direction bitmasks so crossings and corners merge into proper box-drawing
+// junctions; box outlines, arrowheads and labels are written as literal runes
+// on top of them.
+
+const (
+	dirUp = 1 << iota
+	dirDown
+	dirLeft
+	dirRight
+)
+
+const (
+	lineSolid = iota
+	lineDotted
+	lineThick
+)
+
+var junctionRunes = map[uint8]rune{
+	dirUp:                                '│',
+	dirDown:                              '│',
+	dirUp | dirDown:                      '│',
+	dirLeft:                              '─',
+	dirRight:                             '─',
+	dirLeft | dirRight:                   '─',
+	dirDown | dirRight:                   '┌',
+	dirDown | dirLeft:                    '┐',
+	dirUp | dirRight:                     '└',
+	dirUp | dirLeft:                      '┘',
+	dirUp | dirDown | dirRight:           '├',
+	dirUp | dirDown | dirLeft:            '┤',
+	dirDown | dirLeft | dirRight:         '┬',
+	dirUp | dirLeft | dirRight:           '┴',
+	dirUp | dirDown | dirLeft | dirRight: '┼',
+}
+
+type canvas struct {
+	text  map[[2]int]rune
+	lines map[[2]int]uint8
+	style map[[2]int]int
+}
+
+func newCanvas() *canvas {
+	return &canvas{
+		text:  map[[2]int]rune{},
+		lines: map[[2]int]uint8{},
+		style: map[[2]int]int{},
+	}
+}
+
+func (c *canvas) put(x, y int, r rune) {
+	c.text[[2]int{x, y}] = r
+}
+
+func (c *canvas) write(x, y int, s string) {
+	for _, r := range s {
+		c.put(x, y, r)
+		x++
+	}
+}
+
+func (c *canvas) link(x, y int, dirs uint8, style int) {
+	k := [2]int{x, y}
+	c.lines[k] |= dirs
+	if style != lineSolid {
+		c.style[k] = style
+	}
+}
+
+// hline draws a horizontal connector between x1 and x2 (inclusive) on row y.
+// A zero-length line draws nothing, so it never adds a stray junction.
+func (c *canvas) hline(x1, x2, y, style int) {
+	if x1 > x2 {
+		x1, x2 = x2, x1
+	}
+	for x := x1; x < x2; x++ {
+		c.link(x, y, dirRight, style)
+		c.link(x+1, y, dirLeft, style)
+	}
+}
+
+// vline draws a vertical connector between y1 and y2 (inclusive) in column x.
+func (c *canvas) vline(x, y1, y2, style int) {
+	if y1 > y2 {
+		y1, y2 = y2, y1
+	}
+	for y := y1; y < y2; y++ {
+		c.link(x, y, dirDown, style)
+		c.link(x, y+1, dirUp, style)
+	}
+}
+
+// box draws a w×h outline at (x, y) with the given shape and centred label lines.
+func (c *canvas) box(x, y, w, h int, shape byte, label []string) {
+	tl, tr, bl, br, side := '┌', '┐', '└', '┘', '│'
+	switch shape {
+	case '(':
+		tl, tr, bl, br = '╭', '╮', '╰', '╯'
+	case '{':
+		tl, tr, bl, br = '╱', '╲', '╲', '╱'
+	}
+	c.put(x, y, tl)
+	c.put(x+w-1, y, tr)
+	c.put(x, y+h-1, bl)
+	c.put(x+w-1, y+h-1, br)
+	for i := x + 1; i < x+w-1; i++ {
+		c.put(i, y, '─')
+		c.put(i, y+h-1, '─')
+	}
+	for j := y + 1; j < y+h-1; j++ {
+		c.put(x, j, side)
+		c.put(x+w-1, j, side)
+		for i := x + 1; i < x+w-1; i++ {
+			c.put(i, j, ' ')
+		}
+	}
+	for i, l := range label {
+		n := len([]rune(l))
+		c.write(x+(w-n)/2, y+1+i, l)
+	}
+}
+
+// String flattens the canvas into lines with trailing blanks removed.
+func (c *canvas) String() string {
+	maxX, maxY := 0, 0
+	for k := range c.text {
+		maxX, maxY = max(maxX, k[0]), max(maxY, k[1])
+	}
+	for k := range c.lines {
+		maxX, maxY = max(maxX, k[0]), max(maxY, k[1])
+	}
+	var rows []string
+	for y := 0; y <= maxY; y++ {
+		row := make([]rune, maxX+1)
+		for x := range row {
+			k := [2]int{x, y}
+			switch r, ok := c.text[k]; {
+			case ok:
+				row[x] = r
+			case c.lines[k] != 0:
+				row[x] = c.lineRune(k)
+			default:
+				row[x] = ' '
+			}
+		}
+		rows = append(rows, strings.TrimRight(string(row), " "))
+	}
+	return strings.Join(rows, "\n")
+}
+
+func (c *canvas) lineRune(k [2]int) rune {
+	d := c.lines[k]
+	vertical := d&(dirLeft|dirRight) == 0
+	horizontal := d&(dirUp|dirDown) == 0
+	switch c.style[k] {
+	case lineDotted:
+		if vertical {
+			return '┆'
+		}
+		if horizontal {
+			return '┄'
+		}
+	case lineThick:
+		if vertical {
+			return '┃'
+		}
+		if horizontal {
+			return '━'
+		}
+	}
+	return junctionRunes[d]
+}
+
+// renderMermaid lays out a Mermaid flowchart or sequence diagram as Unicode
+// box-and-arrow art. It reports false for unsupported diagram types or source
+// it cannot parse, so the caller can fall back to showing the source.
+func renderMermaid(src string) (string, bool) {
+	var lines []string
+	for _, l := range strings.Split(src, "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "%%") {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	if len(lines) == 0 {
+		return "", false
+	}
+	header := strings.Fields(lines[0])
+	switch header[0] {
+	case "graph", "flowchart":
+		dir := "TD"
+		if len(header) > 1 {
+			dir = strings.ToUpper(header[1])
+		}
+		fc, ok := parseFlowchart(dir, lines[1:])
+		if !ok {
+			return "", false
+		}
+		return fc.render(), true
+	case "sequenceDiagram":
+		sd, ok := parseSequence(lines[1:])
+		if !ok {
+			return "", false
+		}
+		return sd.render(), true
+	}
+	return "", false
+}
+
+// Flowcharts
+
+type mmNode struct {
+	id    string
+	label []string
+	shape byte // '[' rectangle, '(' rounded, '{' decision, 0 for layout dummies
+	rank  int
+	order float64
+	x, y  int
+	w, h  int
+}
+
+type mmEdge struct {
+	from, to  string
+	label     string
+	head      bool // arrowhead at the target
+	tail      bool // arrowhead at the source (<-->)
+	lineStyle int
+}
+
+type flowchart struct {
+	dir   string
+	nodes []*mmNode
+	index map[string]*mmNode
+	edges []mmEdge
+}
+
+// nodeRe matches a node reference with an optional shaped label. Ids may
+// contain single "." or "-" between word characters but never end in one, so
+// an unspaced arrow such as A-->B is not taken as part of the id.
+// Group 1 = id, group 2 = shape and label.
+var nodeRe = regexp.MustCompile(`^(\w+(?:[.-]\w+)*)\s*(\[\[.*?\]\]|\[\(.*?\)\]|\(\[.*?\]\)|\(\(.*?\)\)|\{\{.*?\}\}|\[.*?\]|\(.*?\)|\{.*?\}|>.*?\])?`)
+
+// edgeRe matches an edge operator. Group 1 = "<" for bidirectional edges,
+// group 2 = operator, group 3 = optional |label|.
+var edgeRe = regexp.MustCompile(`^\s*(<)?(-{2,}>|-{3,}|={2,}>|={3,}|-\.+->|-\.+-|~{3,}|--[ox]|==[ox])\s*(?:\|([^|]*)\|)?\s*`)
+
+// textEdgeRe matches the "-- label -->" form. Group 1 = label, group 2 = closing operator.
+var textEdgeRe = regexp.MustCompile(`^\s*(?:--|==|-\.)\s+(.+?)\s+(-{2,}>|-{3,}|={2,}>|={3,}|\.-+>|\.-+)\s*`)
+
+var flowchartSkipRe = regexp.MustCompile(`^(classDef|class|style|linkStyle|click|direction|subgraph|end)\b`)
+
+func parseFlowchart(dir string, lines []string) (*flowchart, bool) {
+	switch dir {
+	case "TB":
+		dir = "TD"
+	case "TD", "BT", "LR", "RL":
+	default:
+		return nil, false
+	}
+	fc := &flowchart{dir: dir, index: map[string]*mmNode{}}
+	for _, line := range lines {
+		for _, stmt := range strings.Split(line, ";") {
+			stmt = strings.TrimSpace(stmt)
+			if stmt == "" || flowchartSkipRe.MatchString(stmt) {
+				continue
+			}
+			if !fc.parseStatement(stmt) {
+				return nil, false
+			}
+		}
+	}
+	return fc, len(fc.nodes) > 0
+}
+
+// parseStatement parses a chain such as `A[Start] --> B & C -->|yes| D`.
+func (fc *flowchart) parseStatement(stmt string) bool {
+	rest := stmt
+	prev, ok := fc.parseNodeGroup(&rest)
+	if !ok {
+		return false
+	}
+	for strings.TrimSpace(rest) != "" {
+		e, ok := parseEdgeOp(&rest)
+		if !ok {
+			return false
+		}
+		next, ok := fc.parseNodeGroup(&rest)
+		if !ok {
+			return false
+		}
+		for _, a := range prev {
+			for _, b := range next {
+				if a == b {
+					return false // self loops cannot be drawn; show the source
+				}
+				edge := e
+				edge.from, edge.to = a, b
+				fc.edges = append(fc.edges, edge)
+			}
+		}
+		prev = next
+	}
+	return true
+}
+
+// parseNodeGroup parses `A` or `A & B & C`, advancing *rest past it.
+func (fc *flowchart) parseNodeGroup(rest *string) ([]string, bool) {
+	var ids []string
+	for {
+		s := strings.TrimSpace(*rest)
+		m := nodeRe.FindStringSubmatch(s)
+		if m == nil {
+			return nil, false
+		}
+		fc.addNode(m[1], m[2])
+		ids = append(ids, m[1])
+		s = strings.TrimSpace(s[len(m[0]):])
+		if !strings.HasPrefix(s, "&") {
+			*rest = s
+			return ids, true
+		}
+		*rest = s[1:]
+	}
+}
+
+func parseEdgeOp(rest *string) (mmEdge, bool) {
+	var e mmEdge
+	var op string
+	if m := textEdgeRe.FindStringSubmatch(*rest); m != nil {
+		e.label, op = m[1], m[2]
+		*rest = (*rest)[len(m[0]):]
+	} else if m := edgeRe.FindStringSubmatch(*rest); m != nil {
+		e.tail = m[1] == "<"
+		op, e.label = m[2], strings.TrimSpace(m[3])
+		*rest = (*rest)[len(m[0]):]
+	} else {
+		return e, false
+	}
+	e.head = strings.HasSuffix(op, ">") || strings.HasSuffix(op, "o") || strings.HasSuffix(op, "x")
+	switch {
+	case strings.Contains(op, "."):
+		e.lineStyle = lineDotted
+	case strings.HasPrefix(op, "="):
+		e.lineStyle = lineThick
+	}
+	e.label = unquote(e.label)
+	return e, true
+}
+
+// addNode registers id, updating its label and shape when given.
+func (fc *flowchart) addNode(id, shaped string) {
+	n, ok := fc.index[id]
+	if !ok {
+		n = &mmNode{id: id, label: []string{id}, shape: '['}
+		fc.index[id] = n
+		fc.nodes = append(fc.nodes, n)
+	}
+	if shaped == "" {
+		return
+	}
+	open := strings.TrimRight(shaped[:min(2, len(shaped))], "\"")
+	switch {
+	case strings.HasPrefix(open, "(") || strings.HasPrefix(open, "[("):
+		n.shape = '('
+	case strings.HasPrefix(open, "{"):
+		n.shape = '{'
+	default:
+		n.shape = '['
+	}
+	text := strings.Trim(shaped, "[](){}>")
+	text = unquote(strings.TrimSpace(text))
+	text = strings.NewReplacer("<br/>", "\n", "<br />", "\n", "<br>", "\n").Replace(text)
+	n.label = strings.Split(text, "\n")
+}
+
+func (fc *flowchart) vertical() bool {
+	return fc.dir == "TD" || fc.dir == "BT"
+}
+
+// segment is one leg of an edge between adjacent layers, possibly through a
+// dummy node inserted for edges spanning several layers.
+type segment struct {
+	a, b      *mmNode // a is in the lower-ranked layer
+	headAtA   bool
+	headAtB   bool
+	label     string
+	lineStyle int
+}
+
+func (fc *flowchart) render() string {
+	fc.assignRanks()
+
+	// Split long edges into per-layer segments through dummy nodes.
+	layers := map[int][]*mmNode{}
+	maxRank := 0
+	for _, n := range fc.nodes {
+		layers[n.rank] = append(layers[n.rank], n)
+		maxRank = max(maxRank, n.rank)
+	}
+	var segs []segment
+	for _, e := range fc.edges {
+		from, to := fc.index[e.from], fc.index[e.to]
+		headAtTo, headAtFrom := e.head, e.tail
+		if from.rank > to.rank {
+			from, to = to, from
+			headAtTo, headAtFrom = headAtFrom, headAtTo
+		}
+		prev := from
+		for r := from.rank + 1; r <= to.rank; r++ {
+			next := to
+			if r < to.rank {
+				next = &mmNode{rank: r}
+				layers[r] = append(layers[r], next)
+			}
+			s := segment{a: prev, b: next, lineStyle: e.lineStyle}
+			if prev == from {
+				s.headAtA = headAtFrom
+				s.label = e.label
+			}
+			if next == to {
+				s.headAtB = headAtTo
+			}
+			segs = append(segs, s)
+			prev = next
+		}
+	}
+
+	fc.orderLayers(layers, maxRank, segs)
+	for _, n := range fc.nodes {
+		n.w, n.h = 4, 3
+		for _, l := range n.label {
+			n.w = max(n.w, len([]rune(l))+4)
+		}
+		n.h = len(n.label) + 2
+	}
+
+	c := newCanvas()
+	if fc.vertical() {
+		fc.layoutVertical(c, layers, maxRank, segs)
+	} else {
+		fc.layoutHorizontal(c, layers, maxRank, segs)
+	}
+	return c.String()
+}
+
+// assignRanks gives every node a layer: the longest path from a source, with
+// back edges (found by DFS) ignored so cycles still lay out.
+func (fc *flowchart) assignRanks() {
+	out := map[string][]string{}
+	for _, e := range fc.edges {
+		out[e.from] = append(out[e.from], e.to)
+	}
+	state := map[string]int{} // 0 unvisited, 1 on stack, 2 done
+	forward := map[[2]string]bool{}
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = 1
+		for _, to := range out[id] {
+			if state[to] == 1 {
+				continue // back edge
+			}
+			forward[[2]string{id, to}] = true
+			if state[to] == 0 {
+				visit(to)
+			}
+		}
+		state[id] = 2
+	}
+	for _, n := range fc.nodes {
+		if state[n.id] == 0 {
+			visit(n.id)
+		}
+	}
+	// Relax ranks until stable; the forward graph is acyclic.
+	for changed := true; changed; {
+		changed = false
+		for _, e := range fc.edges {
+			if !forward[[2]string{e.from, e.to}] {
+				continue
+			}
+			if r := fc.index[e.from].rank + 1; r > fc.index[e.to].rank {
+				fc.index[e.to].rank = r
+				changed = true
+			}
+		}
+	}
+}
+
+// orderLayers reduces crossings with a few barycenter sweeps.
+func (fc *flowchart) orderLayers(layers map[int][]*mmNode, maxRank int, segs []segment) {
+	for r := 0; r <= maxRank; r++ {
+		for i, n := range layers[r] {
+			n.order = float64(i)
+		}
+	}
+	for pass := 0; pass < 4; pass++ {
+		down := pass%2 == 0
+		for step := 1; step <= maxRank; step++ {
+			r := step
+			if !down {
+				r = maxRank - step
+			}
+			sum := map[*mmNode]float64{}
+			cnt := map[*mmNode]float64{}
+			for _, s := range segs {
+				if down && s.b.rank == r {
+					sum[s.b] += s.a.order
+					cnt[s.b]++
+				} else if !down && s.a.rank == r {
+					sum[s.a] += s.b.order
+					cnt[s.a]++
+				}
+			}
+			layer := layers[r]
+			for _, n := range layer {
+				if cnt[n] > 0 {
+					n.order = sum[n] / cnt[n]
+				}
+			}
+			sort.SliceStable(layer, func(i, j int) bool { return layer[i].order < layer[j].order })
+			for i, n := range layer {
+				n.order = float64(i)
+			}
+		}
+	}
+}
+
+// position returns the drawing position of a layer; BT and RL charts run
+// their layers in reverse.
+func (fc *flowchart) position(rank, maxRank int) int {
+	if fc.dir == "BT" || fc.dir == "RL" {
+		return maxRank - rank
+	}
+	return rank
+}
+
+// placeAlong positions the nodes of each layer along the cross axis: every
+// node is centred on the mean centre of its parents where possible, packed in
+// layer order without overlap. pos and size select the x or y coordinate.
+func placeAlong(layers map[int][]*mmNode, maxRank int, segs []segment, spacing func(*mmNode) int, pos func(*mmNode) *int, size func(*mmNode) int) {
+	parents := map[*mmNode][]*mmNode{}
+	for _, s := range segs {
+		parents[s.b] = append(parents[s.b], s.a)
+	}
+	lowest := 0
+	for r := 0; r <= maxRank; r++ {
+		next, placed := 0, false
+		for _, n := range layers[r] {
+			want := next
+			if ps := parents[n]; len(ps) > 0 {
+				sum := 0
+				for _, p := range ps {
+					sum += *pos(p) + size(p)/2
+				}
+				want = sum/len(ps) - size(n)/2
+				if placed {
+					want = max(want, next)
+				}
+			}
+			*pos(n) = want
+			lowest = min(lowest, want)
+			next, placed = want+size(n)+spacing(n), true
+		}
+	}
+	for r := 0; r <= maxRank; r++ {
+		for _, n := range layers[r] {
+			*pos(n) -= lowest
+		}
+	}
+}
+
+// spreadPorts assigns each segment an attachment point along one side of n,
+// spread evenly so edges leaving or entering the same box do not share a stub.
+// The segments are ordered by the position of their far end to avoid crossings
+// right at the box. Dummy nodes have a single port.
+func spreadPorts(n *mmNode, segs []*segment, far func(*segment) int, start, length int, into map[*segment]int) {
+	sort.SliceStable(segs, func(i, j int) bool { return far(segs[i]) < far(segs[j]) })
+	for i, s := range segs {
+		if n.shape == 0 {
+			into[s] = start
+		} else {
+			into[s] = start + 1 + (i+1)*(length-2)/(len(segs)+1)
+		}
+	}
+}
+
+// labelSlots is the room a node's outgoing ports need when their edges carry
+// labels written beside the stub: one column for the line plus the label.
+func labelSlots(segs []*segment) (slots []int, total int, labelled bool) {
+	for _, s := range segs {
+		n := 2
+		if s.label != "" {
+			n = len([]rune(s.label)) + 3
+			labelled = true
+		}
+		slots = append(slots, n)
+		total += n
+	}
+	return slots, total, labelled
+}
+
+// orient returns the segment's ends in drawing order (top-to-bottom or
+// left-to-right) together with their arrowhead flags.
+func (fc *flowchart) orient(s *segment) (first, second *mmNode, headFirst, headSecond bool) {
+	if fc.dir == "BT" || fc.dir == "RL" {
+		return s.b, s.a, s.headAtB, s.headAtA
+	}
+	return s.a, s.b, s.headAtA, s.headAtB
+}
+
+func (fc *flowchart) layoutVertical(c *canvas, layers map[int][]*mmNode, maxRank int, segs []segment) {
+	const gap = 3
+
+	// Rows: each layer is as tall as its tallest node, followed by a gap.
+	height := make([]int, maxRank+1)
+	for r := 0; r <= maxRank; r++ {
+		height[r] = 1
+		for _, n := range layers[r] {
+			if n.shape != 0 {
+				height[r] = max(height[r], n.h)
+			}
+		}
+	}
+	top := make([]int, maxRank+2)
+	for p := 0; p <= maxRank; p++ {
+		top[p+1] = top[p] + height[fc.position(p, maxRank)] + gap
+	}
+	for r := 0; r <= maxRank; r++ {
+		for _, n := range layers[r] {
+			if n.shape == 0 {
+				n.w, n.h = 1, height[r]
+			}
+			n.y = top[fc.position(r, maxRank)]
+		}
+	}
+
+	below := map[*mmNode][]*segment{}
+	above := map[*mmNode][]*segment{}
+	for i := range segs {
+		upper, lower, _, _ := fc.orient(&segs[i])
+		below[upper] = append(below[upper], &segs[i])
+		above[lower] = append(above[lower], &segs[i])
+	}
+	// Widen boxes whose outgoing edges carry labels so every label fits
+	// between its stub and the next one.
+	for n, ss := range below {
+		if _, total, labelled := labelSlots(ss); labelled && n.shape != 0 {
+			n.w = max(n.w, total+2)
+		}
+	}
+	placeAlong(layers, maxRank, segs,
+		func(*mmNode) int { return 3 },
+		func(n *mmNode) *int { return &n.x },
+		func(n *mmNode) int { return n.w })
+
+	centre := func(n *mmNode) int { return n.x + n.w/2 }
+	outX, inX := map[*segment]int{}, map[*segment]int{}
+	for n, ss := range below {
+		spreadPorts(n, ss, func(s *segment) int { _, l, _, _ := fc.orient(s); return centre(l) }, n.x, n.w, outX)
+		if slots, total, labelled := labelSlots(ss); labelled && n.shape != 0 {
+			x := n.x + 1 + (n.w-2-total)/2
+			for i, s := range ss {
+				outX[s] = x
+				x += slots[i]
+			}
+		}
+	}
+	for n, ss := range above {
+		spreadPorts(n, ss, func(s *segment) int { u, _, _, _ := fc.orient(s); return centre(u) }, n.x, n.w, inX)
+	}
+
+	for _, n := range fc.nodes {
+		c.box(n.x, n.y, n.w, n.h, n.shape, n.label)
+	}
+	for i := range segs {
+		s := &segs[i]
+		upper, lower, headUpper, headLower := fc.orient(s)
+		x1, x2 := outX[s], inX[s]
+		from, to := upper.y+upper.h, lower.y-1
+		if upper.shape == 0 {
+			from--
+		} else {
+			c.put(x1, upper.y+upper.h-1, '┬')
+		}
+		if lower.shape == 0 {
+			to++
+		} else {
+			c.put(x2, lower.y, '┴')
+		}
+		mid := lower.y - 2
+		c.vline(x1, from, mid, s.lineStyle)
+		c.hline(x1, x2, mid, s.lineStyle)
+		c.vline(x2, mid, to, s.lineStyle)
+		if headUpper {
+			c.put(x1, upper.y+upper.h, '▲')
+		}
+		if headLower {
+			c.put(x2, lower.y-1, '▼')
+		}
+		if s.label != "" {
+			c.write(x1+2, upper.y+upper.h, s.label)
+		}
+	}
+	for r := 0; r <= maxRank; r++ {
+		for _, n := range layers[r] {
+			if n.shape == 0 {
+				c.vline(n.x, n.y, n.y+n.h-1, lineSolid)
+			}
+		}
+	}
+}
+
+func (fc *flowchart) layoutHorizontal(c *canvas, layers map[int][]*mmNode, maxRank int, segs []segment) {
+	// Columns: each layer is as wide as its widest node, followed by a gap
+	// wide enough for the longest edge label crossing it.
+	width := make([]int, maxRank+1)
+	for r := 0; r <= maxRank; r++ {
+		width[r] = 1
+		for _, n := range layers[r] {
+			if n.shape != 0 {
+				width[r] = max(width[r], n.w)
+			}
+		}
+	}
+	gapAfter := make([]int, maxRank+1) // indexed by drawing position
+	for i := range segs {
+		if segs[i].label != "" {
+			p := min(fc.position(segs[i].a.rank, maxRank), fc.position(segs[i].b.rank, maxRank))
+			gapAfter[p] = max(gapAfter[p], len([]rune(segs[i].label))+2)
+		}
+	}
+	left := make([]int, maxRank+2)
+	for p := 0; p <= maxRank; p++ {
+		left[p+1] = left[p] + width[fc.position(p, maxRank)] + 6 + gapAfter[p]
+	}
+	for r := 0; r <= maxRank; r++ {
+		for _, n := range layers[r] {
+			if n.shape == 0 {
+				n.w, n.h = width[r], 1
+			}
+			n.x = left[fc.position(r, maxRank)]
+		}
+	}
+
+	placeAlong(layers, maxRank, segs,
+		func(*mmNode) int { return 1 },
+		func(n *mmNode) *int { return &n.y },
+		func(n *mmNode) int { return n.h })
+
+	middle := func(n *mmNode) int { return n.y + n.h/2 }
+	rightOf := map[*mmNode][]*segment{}
+	leftOf := map[*mmNode][]*segment{}
+	for i := range segs {
+		first, second, _, _ := fc.orient(&segs[i])
+		rightOf[first] = append(rightOf[first], &segs[i])
+		leftOf[second] = append(leftOf[second], &segs[i])
+	}
+	outY, inY := map[*segment]int{}, map[*segment]int{}
+	for n, ss := range rightOf {
+		spreadPorts(n, ss, func(s *segment) int { _, l, _, _ := fc.orient(s); return middle(l) }, n.y, n.h, outY)
+	}
+	for n, ss := range leftOf {
+		spreadPorts(n, ss, func(s *segment) int { f, _, _, _ := fc.orient(s); return middle(f) }, n.y, n.h, inY)
+	}
+
+	for _, n := range fc.nodes {
+		c.box(n.x, n.y, n.w, n.h, n.shape, n.label)
+	}
+	for i := range segs {
+		s := &segs[i]
+		first, second, headFirst, headSecond := fc.orient(s)
+		y1, y2 := outY[s], inY[s]
+		from, to := first.x+first.w, second.x-1
+		if first.shape == 0 {
+			from--
+		} else {
+			c.put(first.x+first.w-1, y1, '├')
+		}
+		if second.shape == 0 {
+			to++
+		} else {
+			c.put(second.x, y2, '┤')
+		}
+		mid := second.x - 3
+		c.hline(from, mid, y1, s.lineStyle)
+		c.vline(mid, y1, y2, s.lineStyle)
+		c.hline(mid, to, y2, s.lineStyle)
+		if headFirst {
+			c.put(first.x+first.w, y1, '◀')
+		}
+		if headSecond {
+			c.put(second.x-1, y2, '▶')
+		}
+		if s.label != "" {
+			c.write(first.x+first.w+1, y1, " "+s.label+" ")
+		}
+	}
+	for r := 0; r <= maxRank; r++ {
+		for _, n := range layers[r] {
+			if n.shape == 0 {
+				c.hline(n.x, n.x+n.w-1, n.y, lineSolid)
+			}
+		}
+	}
+}
+
+// Sequence diagrams
+
+type seqParticipant struct {
+	id     string
+	label  string
+	center int
+}
+
+type seqEvent struct {
+	kind      string // "msg", "note", "block"
+	from, to  int
+	text      string
+	lineStyle int
+	head      rune // arrowhead rune, 0 for none
+	noteSide  string
+}
+
+type sequence struct {
+	participants []*seqParticipant
+	index        map[string]int
+	events       []seqEvent
+}
+
+var (
+	participantRe = regexp.MustCompile(`^(participant|actor)\s+(.+?)(?:\s+as\s+(.+))?$`)
+	messageRe     = regexp.MustCompile(`^([^\s\-+]+?)\s*(-->>|->>|-->|->|--x|-x|--\)|-\))\s*[+-]?\s*([^\s:]+?)\s*:\s*(.*)$`)
+	noteRe        = regexp.MustCompile(`^(?i:note)\s+(left of|right of|over)\s+([^:]+?)\s*:\s*(.*)$`)
+	blockRe       = regexp.MustCompile(`^(loop|alt|else|opt|par|and|critical|break|rect|end)\b\s*(.*)$`)
+	seqSkipRe     = regexp.MustCompile(`^(activate|deactivate|autonumber|title|box)\b`)
+)
+
+func parseSequence(lines []string) (*sequence, bool) {
+	sd := &sequence{index: map[string]int{}}
+	for _, line := range lines {
+		switch {
+		case seqSkipRe.MatchString(line):
+		case participantRe.MatchString(line):
+			m := participantRe.FindStringSubmatch(line)
+			sd.participant(m[2], m[3])
+		case messageRe.MatchString(line):
+			m := messageRe.FindStringSubmatch(line)
+			ev := seqEvent{kind: "msg", from: sd.participant(m[1], ""), to: sd.participant(m[3], ""), text: m[4]}
+			op := m[2]
+			if strings.HasPrefix(op, "--") {
+				ev.lineStyle = lineDotted
+			}
+			switch {
+			case strings.HasSuffix(op, ">>"):
+				ev.head = '▶'
+			case strings.HasSuffix(op, "x"):
+				ev.head = '×'
+			case strings.HasSuffix(op, ")"):
+				ev.head = '▷'
+			}
+			sd.events = append(sd.events, ev)
+		case noteRe.MatchString(line):
+			m := noteRe.FindStringSubmatch(line)
+			ids := strings.Split(m[2], ",")
+			ev := seqEvent{kind: "note", noteSide: strings.ToLower(m[1]), text: m[3]}
+			ev.from = sd.participant(strings.TrimSpace(ids[0]), "")
+			ev.to = sd.participant(strings.TrimSpace(ids[len(ids)-1]), "")
+			sd.events = append(sd.events, ev)
+		case blockRe.MatchString(line):
+			m := blockRe.FindStringSubmatch(line)
+			text := m[1]
+			if m[2] != "" && m[1] != "rect" {
+				text += " " + m[2]
+			}
+			sd.events = append(sd.events, seqEvent{kind: "block", text: text})
+		default:
+			return nil, false
+		}
+	}
+	return sd, len(sd.participants) > 0
+}
+
+// participant returns the index of id, declaring it on first use.
+func (sd *sequence) participant(id, alias string) int {
+	id = strings.TrimSpace(id)
+	i, ok := sd.index[id]
+	if !ok {
+		i = len(sd.participants)
+		sd.index[id] = i
+		sd.participants = append(sd.participants, &seqParticipant{id: id, label: id})
+	}
+	if alias != "" {
+		sd.participants[i].label = strings.TrimSpace(alias)
+	}
+	return i
+}
+
+func (sd *sequence) render() string {
+	ps := sd.participants
+	boxW := func(i int) int { return len([]rune(ps[i].label)) + 4 }
+
+	// Minimum distance between neighbouring lifelines.
+	gaps := make([]int, len(ps))
+	for i := 1; i < len(ps); i++ {
+		gaps[i] = (boxW(i-1)+1)/2 + (boxW(i)+1)/2 + 2
+	}
+	extra := 0 // room needed right of the last lifeline
+	place := func() {
+		ps[0].center = boxW(0) / 2
+		for i := 1; i < len(ps); i++ {
+			ps[i].center = ps[i-1].center + gaps[i]
+		}
+	}
+	place()
+	need := func(i, j, d int) {
+		if i > j {
+			i, j = j, i
+		}
+		if i == j {
+			if j+1 < len(ps) {
+				gaps[j+1] = max(gaps[j+1], d)
+			} else {
+				extra = max(extra, d)
+			}
+		} else if have := ps[j].center - ps[i].center; have < d {
+			gaps[j] += d - have
+		}
+		place()
+	}
+	for _, ev := range sd.events {
+		n := len([]rune(ev.text))
+		switch {
+		case ev.kind == "msg":
+			need(ev.from, ev.to, n+4)
+		case ev.kind == "note" && ev.noteSide == "right of":
+			need(ev.from, ev.from, n+7)
+		case ev.kind == "note" && ev.noteSide == "left of" && ev.from > 0:
+			need(ev.from-1, ev.from, n+7)
+		}
+	}
+	right := ps[len(ps)-1].center + boxW(len(ps)-1)/2 + extra
+
+	c := newCanvas()
+	drawHeads := func(y int) {
+		for i, p := range ps {
+			w := boxW(i)
+			c.box(p.center-w/2, y, w, 3, '[', []string{p.label})
+		}
+	}
+	drawHeads(0)
+	y := 3
+	for _, ev := range sd.events {
+		switch ev.kind {
+		case "msg":
+			a, b := ps[ev.from].center, ps[ev.to].center
+			if a == b {
+				c.write(a+2, y, ev.text)
+				c.hline(a, a+3, y+1, ev.lineStyle)
+				c.vline(a+3, y+1, y+2, ev.lineStyle)
+				c.hline(a, a+3, y+2, ev.lineStyle)
+				if ev.head != 0 {
+					c.put(a+1, y+2, '◀')
+				}
+				y += 3
+				continue
+			}
+			lo := min(a, b)
+			c.write(lo+(abs(b-a)-len([]rune(ev.text)))/2+1, y, ev.text)
+			c.hline(a, b, y+1, ev.lineStyle)
+			if ev.head != 0 {
+				head := ev.head
+				x := b - 1
+				if b < a {
+					x = b + 1
+					if head == '▶' {
+						head = '◀'
+					} else if head == '▷' {
+						head = '◁'
+					}
+				}
+				c.put(x, y+1, head)
+			}
+			y += 2
+		case "note":
+			w := len([]rune(ev.text)) + 4
+			var x int
+			switch ev.noteSide {
+			case "right of":
+				x = ps[ev.from].center + 2
+			case "left of":
+				x = ps[ev.from].center - 1 - w
+			default:
+				lo, hi := ps[ev.from].center, ps[ev.to].center
+				if lo > hi {
+					lo, hi = hi, lo
+				}
+				w = max(w, hi-lo+5)
+				x = (lo+hi)/2 - w/2
+			}
+			x = max(x, 0)
+			c.box(x, y, w, 3, '[', []string{ev.text})
+			y += 3
+		case "block":
+			c.hline(0, right, y, lineDotted)
+			c.write(0, y, "┄ "+ev.text+" ")
+			y++
+		}
+	}
+	for _, p := range ps {
+		c.vline(p.center, 2, y+1, lineSolid)
+	}
+	drawHeads(y + 1)
+	for _, p := range ps {
+		c.put(p.center, 2, '┬')
+		c.put(p.center, y+1, '┴')
+	}
+	return c.String()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// parseFlowchart tests
+
+func TestParseFlowchart_NodesAndEdges(t *testing.T) {
+	fc, ok := parseFlowchart("TD", []string{"A[Start] --> B{Decide}", "B -->|yes| C(Done)", "B -- no --> A"})
+	if !ok {
+		t.Fatal("expected flowchart to parse")
+	}
+	if len(fc.nodes) != 3 {
+		t.Fatalf("expected 3 nodes, got %d", len(fc.nodes))
+	}
+	if fc.index["A"].label[0] != "Start" || fc.index["B"].shape != '{' || fc.index["C"].shape != '(' {
+		t.Errorf("unexpected node labels/shapes: %+v %+v %+v", fc.index["A"], fc.index["B"], fc.index["C"])
+	}
+	if len(fc.edges) != 3 {
+		t.Fatalf("expected 3 edges, got %d", len(fc.edges))
+	}
+	if fc.edges[1].label != "yes" || fc.edges[2].label != "no" {
+		t.Errorf("expected edge labels 'yes' and 'no', got %q and %q", fc.edges[1].label, fc.edges[2].label)
+	}
+}
+
+func TestParseFlowchart_ChainsAndGroups(t *testing.T) {
+	fc, ok := parseFlowchart("LR", []string{"a --> b & c --> d; d -.-> e"})
+	if !ok {
+		t.Fatal("expected flowchart to parse")
+	}
+	if len(fc.edges) != 5 {
+		t.Fatalf("expected 5 edges (a→b, a→c, b→d, c→d, d→e), got %d", len(fc.edges))
+	}
+	if fc.edges[4].lineStyle != lineDotted {
+		t.Error("expected -.-> to produce a dotted edge")
+	}
+}
+
+func TestParseFlowchart_UnspacedArrows(t *testing.T) {
+	fc, ok := parseFlowchart("TD", []string{"A-->B", "B-->|yes|C[Done]", "my-node.v2==>A", "C-.->D"})
+	if !ok {
+		t.Fatal("expected unspaced arrows to parse")
+	}
+	var got []string
+	for _, e := range fc.edges {
+		got = append(got, e.from+">"+e.to)
+	}
+	if strings.Join(got, " ") != "A>B B>C my-node.v2>A C>D" {
+		t.Errorf("unexpected edges %q", got)
+	}
+	if fc.edges[1].label != "yes" || fc.index["C"].label[0] != "Done" || fc.edges[3].lineStyle != lineDotted {
+		t.Errorf("unexpected edge details: %+v %+v", fc.edges[1], fc.edges[3])
+	}
+}
+
+func TestParseFlowchart_SelfLoopFallsBack(t *testing.T) {
+	if _, ok := parseFlowchart("TD", []string{"A --> B", "A --> A"}); ok {
+		t.Error("expected a self loop to be rejected rather than silently dropped")
+	}
+}
+
+func TestParseFlowchart_RejectsUnknownSyntax(t *testing.T) {
+	if _, ok := parseFlowchart("TD", []string{"A --> B", "this is not mermaid !!"}); ok {
+		t.Error("expected unparseable statement to be rejected")
+	}
+}
+
+// renderMermaid tests
+
+func TestRenderMermaid_FlowchartTD(t *testing.T) {
+	out, ok := renderMermaid("graph TD\n  A[Start] --> B[End]\n")
+	if !ok {
+		t.Fatal("expected flowchart to render")
+	}
+	lines := strings.Split(out, "\n")
+	start, end := -1, -1
+	for i, l := range lines {
+		if strings.Contains(l, "Start") {
+			start = i
+		}
+		if strings.Contains(l, "End") {
+			end = i
+		}
+	}
+	if start < 0 || end < 0 || start >= end {
+		t.Fatalf("expected Start above End, got:\n%s", out)
+	}
+	if !strings.Contains(out, "▼") {
+		t.Errorf("expected a downward arrowhead, got:\n%s", out)
+	}
+}
+
+func TestRenderMermaid_FlowchartLR(t *testing.T) {
+	out, ok := renderMermaid("flowchart LR\n  A --> B\n")
+	if !ok {
+		t.Fatal("expected flowchart to render")
+	}
+	var row string
+	for _, l := range strings.Split(out, "\n") {
+		if strings.Contains(l, "A") {
+			row = l
+		}
+	}
+	if !strings.Contains(row, "B") || strings.Index(row, "A") > strings.Index(row, "B") {
+		t.Errorf("expected A left of B on one row, got:\n%s", out)
+	}
+	if !strings.Contains(row, "▶") {
+		t.Errorf("expected a rightward arrowhead, got:\n%s", out)
+	}
+}
+
+func TestRenderMermaid_UnspacedArrow(t *testing.T) {
+	out, ok := renderMermaid("graph LR\n  A-->B\n")
+	if !ok {
+		t.Fatal("expected A-->B to render")
+	}
+	if !strings.Contains(out, "A") || !strings.Contains(out, "B") {
+		t.Errorf("expected both nodes in output:\n%s", out)
+	}
+}
+
+func TestRenderMermaid_CycleStillLaysOut(t *testing.T) {
+	out, ok := renderMermaid("graph TD\n  A --> B\n  B --> C\n  C --> A\n")
+	if !ok {
+		t.Fatal("expected cyclic flowchart to render")
+	}
+	for _, id := range []string{"A", "B", "C"} {
+		if !strings.Contains(out, id) {
+			t.Errorf("expected node %s in output:\n%s", id, out)
+		}
+	}
+}
+
+func TestRenderMermaid_Sequence(t *testing.T) {
+	src := "sequenceDiagram\n  participant A as Alice\n  A->>Bob: Hello\n  Bob-->>A: Hi\n"
+	out, ok := renderMermaid(src)
+	if !ok {
+		t.Fatal("expected sequence diagram to render")
+	}
+	for _, want := range []string{"Alice", "Bob", "Hello", "Hi", "▶", "◀"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "Alice") != 2 {
+		t.Errorf("expected participant boxes at top and bottom, got:\n%s", out)
+	}
+}
+
+func TestRenderMermaid_UnsupportedType(t *testing.T) {
+	if _, ok := renderMermaid("pie title Pets\n  \"Dogs\" : 10\n"); ok {
+		t.Error("expected pie chart to be unsupported")
+	}
+	if _, ok := renderMermaid(""); ok {
+		t.Error("expected empty source to be unsupported")
+	}
+}
+
+// renderCodeBlock integration
+
+func TestRenderCodeBlock_MermaidDrawsDiagram(t *testing.T) {
+	cb := codeBlock{lang: "mermaid", code: "graph LR\n  A --> B\n"}
+	out := stripANSI(renderCodeBlock(cb, 60, "dark"))
+	if strings.Contains(out, "-->") {
+		t.Errorf("expected diagram instead of source, got:\n%s", out)
+	}
+	if !strings.Contains(out, "── mermaid ──") {
+		t.Errorf("expected mermaid label in border, got:\n%s", out)
+	}
+}
+
+func TestRenderCodeBlock_MermaidFallsBackToSource(t *testing.T) {
+	cb := codeBlock{lang: "mermaid", code: "gantt\n  title Plan\n"}
+	out := stripANSI(renderCodeBlock(cb, 60, "dark"))
+	if !strings.Contains(out, "gantt") {
+		t.Errorf("expected source for unsupported diagram, got:\n%s", out)
+	}
+}
+
+func TestRenderCodeBlock_MermaidTooWideFallsBack(t *testing.T) {
+	cb := codeBlock{lang: "mermaid", code: "graph LR\n  A[A very long node label] --> B[Another very long node label]\n"}
+	out := stripANSI(renderCodeBlock(cb, 30, "notty"))
+	if !strings.Contains(out, "-->") {
+		t.Errorf("expected source when diagram does not fit, got:\n%s", out)
+	}
+}
@@ -2,14 +2,20 @@ package main
 
 import (
 	"fmt"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/alecthomas/chroma/v2/quick"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 const (
@@ -17,7 +23,9 @@ const (
 	footerLines = 1
 )
 
-var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+// ansiEscape matches CSI sequences plus the APC, DCS and OSC strings used for
+// inline images.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]|\x1b[_P\]][^\x07\x1b]*(?:\x07|\x1b\\)`)
 
 // codeBlockRe matches fenced code blocks. Group 1 = language (optional), group 2 = code content.
 // Flags: m (multiline ^/$) and s (dotall — . matches \n).
@@ -26,8 +34,23 @@ var codeBlockRe = regexp.MustCompile("(?ms)^`{3}([a-zA-Z][a-zA-Z0-9_+-]*)?\n(.*?
 // headerRe matches ATX headings. Group 1 = '#' characters (level), group 2 = heading text.
 var headerRe = regexp.MustCompile(`(?m)^(#{1,6})\s+(.+)$`)
 
-// inlineMarkdownRe strips common inline markdown delimiters from heading text.
-var inlineMarkdownRe = regexp.MustCompile("[*_~`]{1,2}")
+// codeBlockPlaceholderRe matches an INCIPIT_CODEBLOCK_N placeholder. Group 1 = index.
+var codeBlockPlaceholderRe = regexp.MustCompile(`INCIPIT_CODEBLOCK_(\d+)`)
+
+// headerPlaceholderRe matches an INCIPIT_HEADER_N placeholder. Group 1 = index.
+var headerPlaceholderRe = regexp.MustCompile(`INCIPIT_HEADER_(\d+)`)
+
+// inlineMarkdownRe matches the emphasis and strikethrough delimiters stripped
+// from heading text. Underscores are handled apart, as they only delimit
+// emphasis at word boundaries.
+var inlineMarkdownRe = regexp.MustCompile(`[*~]+`)
+
+// underscoresRe matches a run of underscores.
+var underscoresRe = regexp.MustCompile(`_+`)
+
+// inlineLinkTextRe matches an inline or reference link or image in heading
+// text. Group 1 = link text.
+var inlineLinkTextRe = regexp.MustCompile(`!?\[([^\]]*)\](?:\([^)]*\)|\[[^\]]*\])`)
 
 func stripANSI(s string) string {
 	return ansiEscape.ReplaceAllString(s, "")
@@ -41,6 +64,11 @@ type codeBlock struct {
 type headerBlock struct {
 	level int
 	text  string
+	done  int // completed task list items in the section
+	total int // task list items in the section
+
+	number string // hierarchical number, e.g. "1.2", when numbering is on
+	anchor string // GitHub anchor slug, when anchors are shown
 }
 
 // extractCodeBlocks pulls fenced code blocks out of md, replacing each with a
@@ -78,6 +106,17 @@ func syntaxHighlight(code, lang, chromaStyle string) string {
 	return buf.String()
 }
 
+// codeBlockColors returns the 256-color background and border indices for code
+// blocks in the given style ("light", or anything else → dark defaults).
+func codeBlockColors(style string) (bg, border string) {
+	switch style {
+	case "light":
+		return "254", "27"
+	default: // dark and anything else
+		return "235", "23"
+	}
+}
+
 // renderCodeBlock renders a single code block with a rounded border, syntax
 // highlighting, and a full background fill across all content lines.
 func renderCodeBlock(cb codeBlock, width int, style string) string {
@@ -89,15 +128,7 @@ func renderCodeBlock(cb codeBlock, width int, style string) string {
 
 	useColor := style != "notty"
 
-	var bgIndex, borderColor string
-	switch style {
-	case "light":
-		bgIndex = "254"
-		borderColor = "27"
-	default: // dark and anything else
-		bgIndex = "235"
-		borderColor = "23"
-	}
+	bgIndex, borderColor := codeBlockColors(style)
 
 	bgOn := fmt.Sprintf("\x1b[48;5;%sm", bgIndex)
 	resetToBg := fmt.Sprintf("\x1b[0;48;5;%sm", bgIndex)
@@ -133,11 +164,20 @@ func renderCodeBlock(cb codeBlock, width int, style string) string {
 		blank = lbar + " " + strings.Repeat(" ", innerWidth) + " " + rbar
 	}
 
-	// Obtain syntax-highlighted (or plain) code lines.
+	// Obtain the drawn diagram for mermaid blocks that fit, otherwise
+	// syntax-highlighted (or plain) code lines.
 	var raw string
-	if useColor {
+	diagram, isDiagram := "", false
+	if cb.lang == "mermaid" {
+		diagram, isDiagram = renderMermaid(cb.code)
+		isDiagram = isDiagram && lipgloss.Width(diagram) <= innerWidth
+	}
+	switch {
+	case isDiagram:
+		raw = diagram
+	case useColor:
 		raw = syntaxHighlight(cb.code, cb.lang, chromaStyleName(style))
-	} else {
+	default:
 		raw = cb.code
 	}
 	raw = strings.TrimRight(raw, "\n")
@@ -167,17 +207,86 @@ func renderCodeBlock(cb codeBlock, width int, style string) string {
 	return strings.Join(out, "\n")
 }
 
+// renderBareCodeBlock renders a code block without a frame: highlighted (or
+// plain) code lines indented under the paragraph margin.
+func renderBareCodeBlock(cb codeBlock, width int, style string) string {
+	const indent = "    "
+	raw := cb.code
+	if cb.lang == "mermaid" {
+		if diagram, ok := renderMermaid(cb.code); ok && lipgloss.Width(diagram) <= width-len(indent) {
+			raw = diagram
+		}
+	} else if style != "notty" {
+		raw = syntaxHighlight(cb.code, cb.lang, chromaStyleName(style))
+	}
+	lines := strings.Split(strings.TrimRight(raw, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = indent + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderFrame draws lines inside a rounded border of the given outer width,
+// with an optional title embedded in the top border like a code block label.
+// Lines are padded (or truncated) to the inner width; ANSI styling inside the
+// lines is preserved. An empty borderColor leaves the border unstyled.
+func renderFrame(title string, lines []string, width int, borderColor string) string {
+	innerWidth := width - 4
+	if innerWidth < 1 {
+		innerWidth = 1
+	}
+
+	bs := lipgloss.NewStyle()
+	if borderColor != "" {
+		bs = bs.Foreground(lipgloss.Color(borderColor))
+	}
+
+	var top string
+	if title != "" {
+		dashes := width - 6 - lipgloss.Width(title)
+		if dashes < 0 {
+			dashes = 0
+		}
+		top = bs.Render("╭── ") + title + bs.Render(" "+strings.Repeat("─", dashes)+"╮")
+	} else {
+		top = bs.Render("╭" + strings.Repeat("─", width-2) + "╮")
+	}
+	bottom := bs.Render("╰" + strings.Repeat("─", width-2) + "╯")
+	bar := bs.Render("│")
+
+	out := []string{top}
+	for _, line := range lines {
+		if lipgloss.Width(line) > innerWidth {
+			line = ansi.Truncate(line, innerWidth, "")
+		}
+		pad := innerWidth - lipgloss.Width(line)
+		out = append(out, bar+" "+line+strings.Repeat(" ", pad)+" "+bar)
+	}
+	out = append(out, bottom)
+	return strings.Join(out, "\n")
+}
+
 // injectCodeBlocks replaces INCIPIT_CODEBLOCK_N placeholder lines in rendered
-// with the fully-rendered code block for each corresponding block.
-func injectCodeBlocks(rendered string, blocks []codeBlock, width int, style string) string {
+// with the fully-rendered code block for each corresponding block, framed
+// unless opts.noBorders is set.
+func injectCodeBlocks(rendered string, blocks []codeBlock, width int, style string, opts renderOptions) string {
 	lines := strings.Split(rendered, "\n")
 	for i, line := range lines {
-		plain := stripANSI(line)
-		for j, cb := range blocks {
-			if strings.Contains(plain, fmt.Sprintf("INCIPIT_CODEBLOCK_%d", j)) {
-				lines[i] = renderCodeBlock(cb, width, style)
-				break
-			}
+		sub := codeBlockPlaceholderRe.FindStringSubmatch(stripANSI(line))
+		if sub == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(sub[1])
+		if n >= len(blocks) {
+			continue
+		}
+		switch cb := blocks[n]; {
+		case cb.lang == "math":
+			lines[i] = renderDisplayMath(cb.code, width, style)
+		case opts.noBorders:
+			lines[i] = renderBareCodeBlock(cb, width, style)
+		default:
+			lines[i] = renderCodeBlock(cb, width, style)
 		}
 	}
 	return strings.Join(lines, "\n")
@@ -198,10 +307,35 @@ func extractHeaders(md string) (string, []headerBlock) {
 	return prose, headers
 }
 
-// stripInlineMarkdown removes common inline markdown delimiters (**, *, _, `, ~~)
-// from a heading text string so lipgloss receives clean plain text.
+// stripInlineMarkdown reduces a heading text string to the plain text it
+// renders as, so lipgloss receives clean text and anchors match GitHub's: links
+// and images keep only their text, emphasis delimiters (**, *, _, ~~) and code
+// span backticks are removed, and code spans are otherwise kept as written.
 func stripInlineMarkdown(s string) string {
-	return strings.TrimSpace(inlineMarkdownRe.ReplaceAllString(s, ""))
+	s = mapOutsideCodeSpans(s, func(t string) string {
+		t = inlineLinkTextRe.ReplaceAllString(t, "$1")
+		return stripEmphasisUnderscores(inlineMarkdownRe.ReplaceAllString(t, ""))
+	})
+	return strings.TrimSpace(strings.ReplaceAll(s, "`", ""))
+}
+
+// stripEmphasisUnderscores removes runs of underscores that open or close
+// emphasis, keeping those inside words such as snake_case.
+func stripEmphasisUnderscores(s string) string {
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }
+	var b strings.Builder
+	last := 0
+	for _, loc := range underscoresRe.FindAllStringIndex(s, -1) {
+		before, _ := utf8.DecodeLastRuneInString(s[:loc[0]])
+		after, _ := utf8.DecodeRuneInString(s[loc[1]:])
+		if loc[0] > 0 && loc[1] < len(s) && isWord(before) && isWord(after) {
+			continue
+		}
+		b.WriteString(s[last:loc[0]])
+		last = loc[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
 }
 
 // headerColors returns the 256-color fg/bg indices and bold flag for a heading
@@ -239,11 +373,27 @@ func headerColors(level int, style string) (fg, bg string, bold bool) {
 	}
 }
 
+// headingText returns the plain text shown for a heading.
+func headingText(h headerBlock) string {
+	text := strings.ReplaceAll(h.text, lineBreak, " ")
+	text = strings.NewReplacer(kbdOpen, "", kbdClose, "", "\u00a0", " ").Replace(text)
+	return stripInlineMarkdown(replaceInlineMath(text, false))
+}
+
 // renderHeader renders a single heading as a pill-shaped lipgloss string.
 // For "notty" style it returns plain text with no ANSI codes.
 func renderHeader(h headerBlock, style string) string {
-	text := stripInlineMarkdown(h.text)
+	text := headingText(h)
+	if h.number != "" {
+		text = h.number + " " + text
+	}
 	if style == "notty" {
+		if h.total > 0 {
+			text += " " + renderTaskCounter(h, style)
+		}
+		if h.anchor != "" {
+			text += " " + renderAnchor(h.anchor, style)
+		}
 		return text
 	}
 	fg, bg, bold := headerColors(h.level, style)
@@ -252,7 +402,14 @@ func renderHeader(h headerBlock, style string) string {
 		Background(lipgloss.Color(bg)).
 		Padding(0, 2).
 		Bold(bold)
-	return s.Render(text)
+	pill := s.Render(text)
+	if h.total > 0 {
+		pill += " " + renderTaskCounter(h, style)
+	}
+	if h.anchor != "" {
+		pill += " " + renderAnchor(h.anchor, style)
+	}
+	return pill
 }
 
 // injectHeaders replaces INCIPIT_HEADER_N placeholder lines in rendered with
@@ -271,28 +428,135 @@ func injectHeaders(rendered string, headers []headerBlock, style string) string
 	return strings.Join(lines, "\n")
 }
 
+// renderOptions carries document-level settings that cannot be inferred from
+// the markdown itself.
+type renderOptions struct {
+	baseDir   string // directory relative image paths are resolved against
+	graphics  string // inline image protocol: "kitty", "iterm", "sixel" or "" for text
+	noBorders bool   // draw code blocks without frames
+
+	// opt-in syntax extensions, switched on in the project config
+	definitionLists bool
+	abbreviations   bool
+	emoji           bool
+
+	numberHeadings bool // prefix headings with 1, 1.2, 1.2.3
+	showAnchors    bool // show each heading's GitHub anchor slug
+
+	collapseDetails bool         // show <details> blocks closed unless listed in openDetails
+	openDetails     map[int]bool // indices of <details> blocks the reader opened
+}
+
 func renderMarkdown(md, style string, width int) string {
-	prose, blocks := extractCodeBlocks(md)
+	return renderDocument(md, style, width, renderOptions{})
+}
+
+// nestedOptions returns the options for a block nested inside a document
+// rendered with opts, such as a callout body. Nested details are always shown
+// open.
+func nestedOptions(opts renderOptions) renderOptions {
+	opts.collapseDetails = false
+	return opts
+}
+
+// renderDocument is renderMarkdown with document-level options.
+func renderDocument(md, style string, width int, opts renderOptions) string {
+	out, _, _ := renderOutline(md, style, width, opts)
+	return out
+}
+
+// heading is a heading as placed in a rendered document.
+type heading struct {
+	level int
+	text  string // plain heading text
+	line  int    // rendered line the heading pill is on
+}
+
+// headingLines returns the headings whose placeholders are still in rendered,
+// with the line each one is on.
+func headingLines(rendered string, headers []headerBlock) []heading {
+	var out []heading
+	for i, line := range strings.Split(rendered, "\n") {
+		sub := headerPlaceholderRe.FindStringSubmatch(stripANSI(line))
+		if sub == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(sub[1])
+		if n < len(headers) {
+			h := headers[n]
+			out = append(out, heading{level: h.level, text: headingText(h), line: i})
+		}
+	}
+	return out
+}
+
+// renderOutline is renderDocument that also reports where each heading and
+// footnote reference ended up. Headings inside callouts and other nested
+// blocks are not included.
+func renderOutline(md, style string, width int, opts renderOptions) (string, []heading, []footnoteRef) {
+	prose, htmlBlocks := extractHTMLBlocks(md)
+	prose, blocks := extractCodeBlocks(prose)
+	prose = convertInlineHTML(prose)
+	prose, notes := extractFootnotes(prose)
+	prose, alerts := extractAlerts(prose)
+	prose, images := extractImages(prose)
+	if opts.abbreviations {
+		prose = expandAbbreviations(prose)
+	}
+	var deflists []definitionList
+	if opts.definitionLists {
+		prose, deflists = extractDefinitionLists(prose)
+	}
 	prose, headers := extractHeaders(prose)
+	countSectionTasks(prose, headers)
+	if opts.numberHeadings {
+		numberHeadings(headers)
+	}
+	if opts.showAnchors {
+		anchorHeadings(headers)
+	}
+	prose, maths := extractMath(prose)
 
-	r, err := glamour.NewTermRenderer(
+	rendererOpts := []glamour.TermRendererOption{
 		glamour.WithStandardStyle(style),
 		glamour.WithWordWrap(width),
-	)
+	}
+	if opts.emoji {
+		rendererOpts = append(rendererOpts, glamour.WithEmoji())
+	}
+	r, err := glamour.NewTermRenderer(rendererOpts...)
 	if err != nil {
-		return md
+		return md, nil, nil
 	}
 	out, err := r.Render(prose)
 	if err != nil {
-		return md
+		return md, nil, nil
 	}
 	out = strings.TrimRight(out, "\n")
-	out = injectCodeBlocks(out, blocks, width, style)
+	out = styleTaskItems(out, style)
+	out = injectCodeBlocks(out, blocks, width, style, opts)
+	out = injectMath(out, maths, width, style)
+	if len(notes) > 0 {
+		out = injectFootnotes(out, width, style)
+	}
+	out = injectAlerts(out, alerts, width, style, opts)
+	out = injectImages(out, images, width, style, opts)
+	out = injectDefinitionLists(out, deflists, width, style, opts)
+	out = injectHTMLBlocks(out, htmlBlocks, width, style, opts)
+	out = finishInlineHTML(out, style)
+	// Nested blocks have no definitions of their own and leave their
+	// references tagged for the document they are part of.
+	var refs []footnoteRef
+	if len(notes) > 0 {
+		out, refs = takeFootnoteRefs(out)
+	}
+	// Headings go in last: each replaces its placeholder line one for one, so
+	// their positions are final.
+	outline := headingLines(out, headers)
 	out = injectHeaders(out, headers, style)
-	return out
+	return out, outline, refs
 }
 
-
 func computeMatches(lines []string, query string) []int {
 	lower := strings.ToLower(query)
 	var result []int
@@ -306,11 +570,21 @@ func computeMatches(lines []string, query string) []int {
 
 type model struct {
 	filename     string
+	source       string // file contents as loaded, front matter included
+	bodyLine     int    // number of source lines before rawMarkdown starts
+	excerpt      bool   // showing one section of the file, which cannot be edited
 	rawMarkdown  string
 	glamourStyle string
+	opts         renderOptions
+	frontMatter  *frontMatter
+	showMeta     bool
 
 	viewport  viewport.Model
 	ready     bool
+	width     int // requested render width, 0 to follow the window
+	maxWidth  int // reading width, 0 for defaultReadingWidth
+	reading   bool
+	window    int // terminal width
 	lastWidth int
 
 	// search state
@@ -320,14 +594,123 @@ type model struct {
 	matchLines  []int    // rendered line indices of matches
 	matchIdx    int
 	noMatches   bool
+
+	// footnote state
+	footnoteDefs   map[int]int   // footnote number → rendered definition line
+	noteRefs       []footnoteRef // references by line of content
+	footnoteRefs   []footnoteRef // references by viewport line
+	footnoteLast   int           // index in footnoteRefs of the last one followed, -1 if none
+	footnoteReturn int           // offset to return to after a jump, -1 if none
+
+	// task state
+	rendered   string     // viewport content without the task cursor
+	tasks      []taskItem // task list items in rawMarkdown
+	taskLines  []int      // rendered line of each task
+	taskMode   bool
+	taskCursor int
+	taskErr    string
+
+	// folding state
+	content      string       // rendered document before folding
+	headings     []heading    // headings of content, with their lines
+	sections     []*section   // section tree of content
+	folded       map[int]bool // indices into headings of folded sections
+	visibleLines []int        // index in content of each viewport line
+	details      bool         // content has a collapsible details block
+	pendingZ     bool         // "z" pressed, waiting for a/M/R
+
+	// search across documents
+	root      string // directory searched, the document's own by default
+	docSearch docSearch
 }
 
 func newModel(filename, rawMarkdown, glamourStyle string) model {
-	return model{
+	m := model{
 		filename:     filename,
-		rawMarkdown:  rawMarkdown,
 		glamourStyle: glamourStyle,
+		opts: renderOptions{
+			baseDir:         filepath.Dir(filename),
+			collapseDetails: true,
+			openDetails:     map[int]bool{},
+		},
+		showMeta: true,
+		folded:   map[int]bool{},
+		root:     filepath.Dir(filename),
+
+		footnoteLast:   -1,
+		footnoteReturn: -1,
+	}
+	m.setSource(rawMarkdown)
+	return m
+}
+
+// setSource loads the file contents, splitting off any front matter.
+func (m *model) setSource(source string) {
+	body, fm := extractFrontMatter(source)
+	m.source = source
+	m.rawMarkdown = body
+	m.frontMatter = fm
+	m.bodyLine = strings.Count(source[:len(source)-len(body)], "\n")
+	m.tasks = scanTasks(body)
+}
+
+// load replaces the document with another file's contents, dropping the
+// state tied to the old one.
+func (m *model) load(filename, content string) {
+	m.filename = filename
+	m.opts.baseDir = filepath.Dir(filename)
+	m.opts.openDetails = map[int]bool{}
+	m.excerpt = false
+	m.folded = map[int]bool{}
+	m.footnoteReturn = -1
+	m.taskMode = false
+	m.viewport.GotoTop()
+	m.setSource(content)
+}
+
+// title returns the front matter title when present, else the filename.
+func (m model) title() string {
+	if t := m.frontMatter.get("title"); t != "" {
+		return t
+	}
+	return m.filename
+}
+
+// contentWidth returns the width the document is rendered at in the current
+// window: the requested width, narrowed to the reading width in reading mode.
+// footerHelp returns the key hints for the footer that fit in width, leaving
+// out keys for things the document does not have.
+func (m model) footerHelp(width int) string {
+	help := " ↑/k ↓/j  g/G  / search"
+	for _, k := range []struct {
+		show bool
+		hint string
+	}{
+		{m.frontMatter != nil, "m meta"},
+		{len(m.headings) > 0, "z fold"},
+		{m.details, "o open"},
+		{len(m.noteRefs) > 0, "F notes"},
+		{len(m.tasks) > 0 || len(m.taskLines) > 0, "t tasks"},
+		{true, "w width"},
+		{true, "S all docs"},
+	} {
+		if k.show && lipgloss.Width(help+"  "+k.hint+"  q quit") <= width {
+			help += "  " + k.hint
+		}
 	}
+	return help + "  q quit"
+}
+
+func (m model) contentWidth() int {
+	w := pagerWidth(m.width, m.window)
+	if m.reading {
+		reading := m.maxWidth
+		if reading == 0 {
+			reading = defaultReadingWidth
+		}
+		w = min(w, reading)
+	}
+	return w
 }
 
 func (m model) Init() tea.Cmd {
@@ -337,17 +720,218 @@ func (m model) Init() tea.Cmd {
 // applyContent renders markdown at the given width and populates the viewport.
 // Preserves scroll position across calls (e.g. on resize).
 func (m *model) applyContent(width int) {
-	rendered := renderMarkdown(m.rawMarkdown, m.glamourStyle, width)
+	rendered, headings, refs := renderOutline(m.rawMarkdown, m.glamourStyle, width, m.opts)
+	if card := renderFrontMatter(m.frontMatter, width, m.glamourStyle); m.showMeta && card != "" {
+		rendered = card + "\n" + rendered
+		offset := strings.Count(card, "\n") + 1
+		for i := range headings {
+			headings[i].line += offset
+		}
+		for i := range refs {
+			refs[i].line += offset
+		}
+	}
 	m.lastWidth = width
+	m.content = rendered
+	m.headings = headings
+	m.sections = sectionTree(headings, strings.Count(rendered, "\n")+1)
+	m.noteRefs = refs
+	m.details = slices.ContainsFunc(strings.Split(stripANSI(rendered), "\n"), detailsSummaryRe.MatchString)
 	savedOffset := m.viewport.YOffset
-	m.viewport.SetContent(rendered)
+	m.showContent()
 	m.viewport.YOffset = savedOffset
+}
+
+// showContent fills the viewport with the unfolded parts of the rendered
+// document and refreshes everything indexed by viewport line.
+func (m *model) showContent() {
+	rendered, visible := foldContent(m.content, m.sections, m.folded, m.glamourStyle)
+	m.visibleLines = visible
+	m.viewport.SetContent(rendered)
 	m.searchLines = strings.Split(stripANSI(rendered), "\n")
+	m.footnoteDefs = footnoteDefLines(m.searchLines)
+	m.footnoteRefs = nil
+	m.footnoteLast = -1
+	shown := map[int]int{}
+	for i, line := range visible {
+		shown[line] = i
+	}
+	for _, r := range m.noteRefs {
+		if i, ok := shown[r.line]; ok {
+			m.footnoteRefs = append(m.footnoteRefs, footnoteRef{line: i, n: r.n})
+		}
+	}
+	m.rendered = rendered
+	m.taskLines = renderedTaskLines(m.searchLines)
+	if m.taskMode {
+		m.showTaskCursor()
+	}
 	if m.searchQuery != "" {
 		m.matchLines = computeMatches(m.searchLines, m.searchQuery)
 	}
 }
 
+// followFootnote jumps from a footnote reference on screen to its definition,
+// or back to where the last jump started. Each jump from the same screen
+// follows the next reference on it.
+func (m *model) followFootnote() {
+	if m.footnoteReturn >= 0 {
+		m.viewport.SetYOffset(m.footnoteReturn)
+		m.footnoteReturn = -1
+		return
+	}
+	top := m.viewport.YOffset
+	i, ok := nextFootnoteRef(m.footnoteRefs, top, top+m.viewport.Height, m.footnoteLast, m.footnoteDefs)
+	if !ok {
+		return
+	}
+	m.footnoteLast = i
+	m.footnoteReturn = top
+	m.viewport.GotoTop()
+	m.viewport.LineDown(m.footnoteDefs[m.footnoteRefs[i].n])
+}
+
+// showTaskCursor marks the selected task in the viewport and scrolls it into
+// view.
+func (m *model) showTaskCursor() {
+	if m.taskCursor >= len(m.taskLines) {
+		m.taskCursor = max(len(m.taskLines)-1, 0)
+	}
+	if len(m.taskLines) == 0 {
+		m.viewport.SetContent(m.rendered)
+		return
+	}
+	line := m.taskLines[m.taskCursor]
+	m.viewport.SetContent(markTaskCursor(m.rendered, line, m.glamourStyle))
+	if line < m.viewport.YOffset || line >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(line - m.viewport.Height/2)
+	}
+}
+
+// toggleFold folds or unfolds the section the top line on screen belongs to,
+// keeping its heading at the top.
+func (m *model) toggleFold() {
+	if m.viewport.YOffset >= len(m.visibleLines) {
+		return
+	}
+	i := headingAt(m.headings, m.visibleLines[m.viewport.YOffset])
+	if i < 0 {
+		// Above the first heading: take it if it is on screen.
+		bottom := min(m.viewport.YOffset+m.viewport.Height, len(m.visibleLines)) - 1
+		if len(m.headings) == 0 || m.headings[0].line > m.visibleLines[bottom] {
+			return
+		}
+		i = 0
+	}
+	if m.folded[i] {
+		delete(m.folded, i)
+	} else {
+		m.folded[i] = true
+	}
+	m.showContent()
+	for row, line := range m.visibleLines {
+		if line == m.headings[i].line {
+			m.viewport.SetYOffset(row)
+			break
+		}
+	}
+}
+
+// setFoldAll folds every section when fold is true, else unfolds them all.
+func (m *model) setFoldAll(fold bool) {
+	top := 0
+	if m.viewport.YOffset < len(m.visibleLines) {
+		top = m.visibleLines[m.viewport.YOffset]
+	}
+	m.folded = map[int]bool{}
+	if fold {
+		for i := range m.headings {
+			m.folded[i] = true
+		}
+	}
+	m.showContent()
+	// Stay at the same place in the document, or the heading folding it.
+	row := 0
+	for r, line := range m.visibleLines {
+		if line > top {
+			break
+		}
+		row = r
+	}
+	m.viewport.SetYOffset(row)
+}
+
+// enterTaskMode selects the first task on screen, or the first task after it.
+// Folded sections are opened first so every task can be reached.
+func (m *model) enterTaskMode() {
+	if len(m.taskLines) == 0 && len(m.tasks) == 0 {
+		return
+	}
+	if len(m.folded) > 0 {
+		m.setFoldAll(false)
+	}
+	if len(m.taskLines) == 0 {
+		return
+	}
+	m.taskMode = true
+	m.taskErr = ""
+	m.taskCursor = 0
+	for i, line := range m.taskLines {
+		if line >= m.viewport.YOffset {
+			m.taskCursor = i
+			break
+		}
+	}
+	m.showTaskCursor()
+}
+
+// toggleSelectedTask flips the selected task in the source file and
+// re-renders.
+func (m *model) toggleSelectedTask() {
+	if m.excerpt {
+		m.taskErr = "tasks cannot be saved from a --section view"
+		return
+	}
+	if len(m.tasks) != len(m.taskLines) {
+		m.taskErr = "cannot match tasks to the source"
+		return
+	}
+	t := m.tasks[m.taskCursor]
+	updated, err := toggleTaskInFile(m.filename, m.source, m.bodyLine+t.line)
+	if err != nil {
+		m.taskErr = err.Error()
+		return
+	}
+	m.taskErr = ""
+	m.setSource(updated)
+	m.applyContent(m.lastWidth)
+}
+
+// updateTasks handles keys in task mode.
+func (m model) updateTasks(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "esc", "t":
+		m.taskMode = false
+		m.taskErr = ""
+		m.viewport.SetContent(m.rendered)
+	case "j", "down":
+		if m.taskCursor < len(m.taskLines)-1 {
+			m.taskCursor++
+		}
+		m.showTaskCursor()
+	case "k", "up":
+		if m.taskCursor > 0 {
+			m.taskCursor--
+		}
+		m.showTaskCursor()
+	case " ":
+		m.toggleSelectedTask()
+	}
+	return m, nil
+}
+
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var (
 		cmd  tea.Cmd
@@ -357,19 +941,38 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		if !m.ready {
-			m.viewport = viewport.New(msg.Width, msg.Height-headerLines-footerLines)
+			m.window = msg.Width
+			m.viewport = viewport.New(m.contentWidth(), msg.Height-headerLines-footerLines)
 			m.viewport.YPosition = headerLines
-			m.applyContent(msg.Width)
+			m.applyContent(m.contentWidth())
 			m.ready = true
 		} else {
+			m.window = msg.Width
 			m.viewport.Height = msg.Height - headerLines - footerLines
-			if msg.Width != m.lastWidth {
-				m.applyContent(msg.Width)
+			if w := m.contentWidth(); w != m.lastWidth {
+				m.applyContent(w)
 			}
-			m.viewport.Width = msg.Width
+			m.viewport.Width = m.contentWidth()
 		}
 
+	case grepResultMsg:
+		if msg.id != m.docSearch.id {
+			return m, nil
+		}
+		m.docSearch.results = append(m.docSearch.results, msg.result)
+		return m, waitForResult(msg.id, msg.results)
+
+	case grepDoneMsg:
+		if msg.id == m.docSearch.id {
+			m.docSearch.done = true
+			m.docSearch.cancel = nil
+		}
+		return m, nil
+
 	case tea.KeyMsg:
+		if m.docSearch.typing || m.docSearch.active {
+			return m.updateDocSearch(msg)
+		}
 		if m.searching {
 			switch {
 			case msg.Type == tea.KeyEnter:
@@ -401,9 +1004,28 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, tea.Batch(cmds...)
 		}
+		if m.taskMode {
+			return m.updateTasks(msg)
+		}
 
 		// Normal pager mode
+		if m.pendingZ {
+			m.pendingZ = false
+			switch msg.String() {
+			case "a":
+				m.toggleFold()
+				return m, nil
+			case "M":
+				m.setFoldAll(true)
+				return m, nil
+			case "R":
+				m.setFoldAll(false)
+				return m, nil
+			}
+		}
 		switch msg.String() {
+		case "z":
+			m.pendingZ = true
 		case "q", "ctrl+c":
 			return m, tea.Quit
 		case "g":
@@ -413,6 +1035,30 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "/":
 			m.searching = true
 			m.noMatches = false
+		case "S":
+			m.docSearch.typing = true
+			m.docSearch.query = ""
+			m.docSearch.err = ""
+		case "F":
+			m.followFootnote()
+		case "t":
+			m.enterTaskMode()
+		case "w":
+			m.reading = !m.reading
+			m.viewport.Width = m.contentWidth()
+			m.applyContent(m.viewport.Width)
+		case "o":
+			top := m.viewport.YOffset
+			lines := strings.Split(stripANSI(m.content), "\n")
+			if n, ok := detailsAt(lines, m.visibleLines, top, top+m.viewport.Height); ok {
+				m.opts.openDetails[n] = !m.opts.openDetails[n]
+				m.applyContent(m.lastWidth)
+			}
+		case "m":
+			if m.frontMatter != nil {
+				m.showMeta = !m.showMeta
+				m.applyContent(m.lastWidth)
+			}
 		case "n":
 			if len(m.matchLines) > 0 {
 				m.matchIdx = (m.matchIdx + 1) % len(m.matchLines)
@@ -438,25 +1084,55 @@ func (m model) View() string {
 		return "\n  Loading..."
 	}
 
-	// Header: bold filename
+	// Header: bold document title (front matter title or filename), followed
+	// by the path of headings enclosing the top line on screen
 	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("99"))
+	crumbStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	var path []string
+	if m.viewport.YOffset < len(m.visibleLines) {
+		path = sectionPath(m.headings, m.visibleLines[m.viewport.YOffset])
+	}
+	crumb, hasTitle := breadcrumb(m.title(), path, m.window-1)
+	if hasTitle {
+		crumb = headerStyle.Render(m.title()) + crumbStyle.Render(strings.TrimPrefix(crumb, m.title()))
+	} else {
+		crumb = crumbStyle.Render(crumb)
+	}
+	if m.docSearch.active {
+		crumb = headerStyle.Render("Search: "+m.docSearch.query) + crumbStyle.Render("  in "+m.root)
+	}
 	header := lipgloss.NewStyle().
-		Width(m.viewport.Width).
-		Render(" " + headerStyle.Render(m.filename))
+		Width(m.window).
+		Render(" " + crumb)
 
 	// Footer
 	var footerContent string
 	switch {
+	case m.docSearch.typing:
+		footerContent = "search docs: " + m.docSearch.query + "_"
+	case m.docSearch.active && m.docSearch.err != "":
+		footerContent = " " + m.docSearch.err
+	case m.docSearch.active:
+		files := len(m.docSearch.results)
+		footerContent = fmt.Sprintf(" %d matches in %d files", m.docSearch.hits(), files)
+		if !m.docSearch.done {
+			footerContent += ", searching…"
+		}
+		footerContent += "  j/k move  enter open  esc back"
 	case m.searching:
 		footerContent = "/" + m.searchQuery + "_"
+	case m.taskMode && m.taskErr != "":
+		footerContent = " " + m.taskErr
+	case m.taskMode:
+		footerContent = fmt.Sprintf(" task %d/%d  j/k move  space toggle  esc done", m.taskCursor+1, len(m.taskLines))
 	case m.noMatches && m.searchQuery != "":
 		footerContent = fmt.Sprintf(" no matches: %s", m.searchQuery)
 	case len(m.matchLines) > 0:
 		footerContent = fmt.Sprintf(" %d/%d: %s", m.matchIdx+1, len(m.matchLines), m.searchQuery)
 	default:
-		help := " ↑/k ↓/j  g/G  / search  q quit"
 		pct := fmt.Sprintf("  %3.f%% ", m.viewport.ScrollPercent()*100)
-		gap := m.viewport.Width - lipgloss.Width(help) - lipgloss.Width(pct)
+		help := m.footerHelp(m.window - lipgloss.Width(pct))
+		gap := m.window - lipgloss.Width(help) - lipgloss.Width(pct)
 		if gap < 0 {
 			gap = 0
 		}
@@ -465,8 +1141,16 @@ func (m model) View() string {
 
 	footer := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("241")).
-		Width(m.viewport.Width).
+		Width(m.window).
 		Render(footerContent)
 
-	return fmt.Sprintf("%s\n%s\n%s", header, m.viewport.View(), footer)
+	body := m.viewport.View()
+	if m.docSearch.active {
+		return fmt.Sprintf("%s\n%s\n%s", header, m.docSearchView(), footer)
+	}
+	if margin := centerMargin(m.viewport.Width, m.window); margin > 0 {
+		pad := strings.Repeat(" ", margin)
+		body = pad + strings.ReplaceAll(body, "\n", "\n"+pad)
+	}
+	return fmt.Sprintf("%s\n%s\n%s", header, body, footer)
 }
@@ -404,13 +404,18 @@ func TestRenderCodeBlock_NottyNoBorderColor(t *testing.T) {
 func TestInjectCodeBlocks_ReplacesPlaceholder(t *testing.T) {
 	blocks := []codeBlock{{lang: "go", code: "x := 1\n"}}
 	rendered := "Some prose\n\n  INCIPIT_CODEBLOCK_0\n\nMore prose"
-	out := injectCodeBlocks(rendered, blocks, 60, "dark")
+	out := injectCodeBlocks(rendered, blocks, 60, "dark", renderOptions{})
 	if strings.Contains(stripANSI(out), "INCIPIT_CODEBLOCK_0") {
 		t.Error("expected placeholder to be replaced")
 	}
 	if !strings.Contains(out, "╭") {
 		t.Error("expected code block border in output")
 	}
+
+	bare := stripANSI(injectCodeBlocks(rendered, blocks, 60, "dark", renderOptions{noBorders: true}))
+	if strings.Contains(bare, "╭") || !strings.Contains(bare, "    x := 1") {
+		t.Errorf("expected an indented code block without a frame, got %q", bare)
+	}
 }
 
 // End-to-end tests
@@ -480,3 +485,14 @@ func TestRenderMarkdown_HeaderH3LightPill(t *testing.T) {
 		t.Error("H3 light: expected no '### ' prefix in output")
 	}
 }
+
+func TestRenderDocument_NoBorders(t *testing.T) {
+	md := "Text.\n\n```go\nx := 1\n```\n"
+	out := stripANSI(renderDocument(md, "notty", 60, renderOptions{noBorders: true}))
+	if strings.ContainsAny(out, "╭╰│") {
+		t.Errorf("expected no code block frame, got %q", out)
+	}
+	if !strings.Contains(out, "    x := 1") {
+		t.Errorf("expected the code indented, got %q", out)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sourceHeading is an ATX heading found in markdown source.
+type sourceHeading struct {
+	level int
+	text  string   // plain heading text
+	slug  string   // unique GitHub anchor
+	line  int      // 0-based line in the source
+	path  []string // texts of the enclosing headings and this one, outermost first
+}
+
+// scanHeadings returns the ATX headings in md outside fenced code blocks.
+func scanHeadings(md string) []sourceHeading {
+	var headings []sourceHeading
+	var stack []sourceHeading
+	slugs := slugger{}
+	fence := ""
+	for i, line := range strings.Split(md, "\n") {
+		if sub := fenceRe.FindStringSubmatch(line); sub != nil {
+			if fence == "" {
+				fence = sub[1]
+			} else if sub[1] == fence {
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+		sub := headerRe.FindStringSubmatch(line)
+		if sub == nil {
+			continue
+		}
+		text := headingText(headerBlock{text: sub[2]})
+		h := sourceHeading{level: len(sub[1]), text: text, slug: slugs.slug(text), line: i}
+		for len(stack) > 0 && stack[len(stack)-1].level >= h.level {
+			stack = stack[:len(stack)-1]
+		}
+		stack = append(stack, h)
+		for _, s := range stack {
+			h.path = append(h.path, s.text)
+		}
+		headings = append(headings, h)
+	}
+	return headings
+}
+
+// matchesSection reports whether h is the section named by query: a heading
+// text or anchor slug, or a "/"-separated path ending at h such as
+// "Usage/Options". Text matching ignores case.
+func matchesSection(h sourceHeading, query string) bool {
+	if strings.EqualFold(h.text, query) || h.slug == query {
+		return true
+	}
+	parts := strings.Split(query, "/")
+	if len(parts) < 2 || len(parts) > len(h.path) {
+		return false
+	}
+	tail := h.path[len(h.path)-len(parts):]
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if !strings.EqualFold(tail[i], p) && headingSlug(tail[i]) != p {
+			return false
+		}
+	}
+	return true
+}
+
+// findSection returns the first section of md named by query: its heading and
+// everything up to the next heading of the same or a higher level.
+func findSection(md, query string) (string, bool) {
+	start, end, ok := sectionRange(md, query)
+	if !ok {
+		return "", false
+	}
+	lines := strings.Split(md, "\n")
+	return strings.TrimRight(strings.Join(lines[start:end], "\n"), "\n") + "\n", true
+}
+
+// sectionRange returns the lines [start, end) of md holding the first section
+// named by query.
+func sectionRange(md, query string) (start, end int, ok bool) {
+	query = strings.TrimSpace(query)
+	headings := scanHeadings(md)
+	for i, h := range headings {
+		if !matchesSection(h, query) {
+			continue
+		}
+		end := strings.Count(md, "\n") + 1
+		for _, next := range headings[i+1:] {
+			if next.level <= h.level {
+				end = next.line
+				break
+			}
+		}
+		return h.line, end, true
+	}
+	return 0, 0, false
+}
+
+// suggestSections returns up to five section paths of md that look like
+// query, closest first.
+func suggestSections(md, query string) []string {
+	parts := strings.Split(query, "/")
+	want := strings.ToLower(strings.TrimSpace(parts[len(parts)-1]))
+	type candidate struct {
+		path string
+		dist int
+	}
+	var candidates []candidate
+	seen := map[string]bool{}
+	for _, h := range scanHeadings(md) {
+		text := strings.ToLower(h.text)
+		dist := editDistance(text, want)
+		if strings.Contains(text, want) || strings.Contains(want, text) {
+			dist = min(dist, 1)
+		}
+		path := strings.Join(h.path, "/")
+		if dist > max(2, len([]rune(want))/3) || seen[path] {
+			continue
+		}
+		seen[path] = true
+		candidates = append(candidates, candidate{path, dist})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].dist < candidates[j].dist })
+	var out []string
+	for _, c := range candidates[:min(len(candidates), 5)] {
+		out = append(out, c.path)
+	}
+	return out
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+// sectionNotFound describes a failed section lookup, with suggestions.
+func sectionNotFound(md, query string) string {
+	msg := fmt.Sprintf("no section matches %q", query)
+	if s := suggestSections(md, query); len(s) > 0 {
+		msg += "\ndid you mean:\n  " + strings.Join(s, "\n  ")
+	}
+	return msg
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const sectionDoc = "# Tool\n\nIntro.\n\n## Installation\n\nRun make.\n\n### From source\n\nClone it.\n\n```sh\n# not a heading\n```\n\n## Usage\n\nRun it.\n\n### Options\n\nFlags.\n\n## Usage\n\nAgain.\n"
+
+func TestFindSection_ByText(t *testing.T) {
+	got, ok := findSection(sectionDoc, "installation")
+	if !ok {
+		t.Fatal("expected a match")
+	}
+	want := "## Installation\n\nRun make.\n\n### From source\n\nClone it.\n\n```sh\n# not a heading\n```\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFindSection_ByPath(t *testing.T) {
+	got, ok := findSection(sectionDoc, "Usage/Options")
+	if !ok || got != "### Options\n\nFlags.\n" {
+		t.Errorf("expected the Options subsection, got %q (ok %v)", got, ok)
+	}
+	if _, ok := findSection(sectionDoc, "Installation/Options"); ok {
+		t.Error("expected no match for a path that does not exist")
+	}
+}
+
+func TestFindSection_BySlug(t *testing.T) {
+	got, ok := findSection(sectionDoc, "from-source")
+	if !ok || !strings.HasPrefix(got, "### From source") {
+		t.Errorf("expected a slug match, got %q (ok %v)", got, ok)
+	}
+	got, ok = findSection(sectionDoc, "usage-1")
+	if !ok || got != "## Usage\n\nAgain.\n" {
+		t.Errorf("expected the deduplicated slug to pick the second Usage, got %q (ok %v)", got, ok)
+	}
+}
+
+func TestFindSection_SkipsFencedHeadings(t *testing.T) {
+	if _, ok := findSection(sectionDoc, "not a heading"); ok {
+		t.Error("expected headings inside code fences to be ignored")
+	}
+}
+
+func TestSuggestSections(t *testing.T) {
+	got := suggestSections(sectionDoc, "Instalation")
+	if len(got) == 0 || got[0] != "Tool/Installation" {
+		t.Errorf("expected Installation suggested first, got %v", got)
+	}
+	if got := suggestSections(sectionDoc, "zzzzzz"); len(got) != 0 {
+		t.Errorf("expected no suggestions, got %v", got)
+	}
+	msg := sectionNotFound(sectionDoc, "option")
+	if !strings.Contains(msg, `no section matches "option"`) || !strings.Contains(msg, "Tool/Usage/Options") {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// sgrRe matches an SGR escape sequence. Group 1 = parameters.
+var sgrRe = regexp.MustCompile(`\x1b\[([0-9;]*)m`)
+
+// Geometry of an SVG screenshot, in pixels.
+const (
+	svgFontSize   = 14
+	svgCellWidth  = 8.4 // 0.6em, the advance of common monospace fonts
+	svgLineHeight = 18
+	svgPadding    = 16
+	svgTitleBar   = 32 // height of the window chrome title bar
+)
+
+// cellStyle is the SGR state of a run of terminal cells. Colors are hex, ""
+// for the default.
+type cellStyle struct {
+	fg, bg    string
+	bold      bool
+	faint     bool
+	italic    bool
+	underline bool
+	strike    bool
+	reverse   bool
+}
+
+// styledRun is text drawn in one style, starting at a cell column.
+type styledRun struct {
+	col   int
+	cells int
+	text  string
+	style cellStyle
+}
+
+// sgrColor reads an extended color (38;5;n or 38;2;r;g;b) from params
+// starting after the 38/48, returning the hex color and the number of
+// parameters consumed.
+func sgrColor(params []int) (string, int) {
+	if len(params) >= 2 && params[0] == 5 {
+		return xtermHex(strconv.Itoa(params[1])), 2
+	}
+	if len(params) >= 4 && params[0] == 2 {
+		return fmt.Sprintf("#%02x%02x%02x", params[1]&0xff, params[2]&0xff, params[3]&0xff), 4
+	}
+	return "", len(params)
+}
+
+// applySGR updates s with the parameters of one SGR sequence.
+func applySGR(s cellStyle, raw string) cellStyle {
+	var params []int
+	for _, p := range strings.Split(raw, ";") {
+		n, _ := strconv.Atoi(p) // an empty parameter means 0
+		params = append(params, n)
+	}
+	for i := 0; i < len(params); i++ {
+		switch p := params[i]; {
+		case p == 0:
+			s = cellStyle{}
+		case p == 1:
+			s.bold = true
+		case p == 2:
+			s.faint = true
+		case p == 3:
+			s.italic = true
+		case p == 4:
+			s.underline = true
+		case p == 7:
+			s.reverse = true
+		case p == 9:
+			s.strike = true
+		case p == 22:
+			s.bold, s.faint = false, false
+		case p == 23:
+			s.italic = false
+		case p == 24:
+			s.underline = false
+		case p == 27:
+			s.reverse = false
+		case p == 29:
+			s.strike = false
+		case p >= 30 && p <= 37:
+			s.fg = xtermBase[p-30]
+		case p >= 90 && p <= 97:
+			s.fg = xtermBase[p-90+8]
+		case p >= 40 && p <= 47:
+			s.bg = xtermBase[p-40]
+		case p >= 100 && p <= 107:
+			s.bg = xtermBase[p-100+8]
+		case p == 39:
+			s.fg = ""
+		case p == 49:
+			s.bg = ""
+		case p == 38 || p == 48:
+			color, n := sgrColor(params[i+1:])
+			if p == 38 {
+				s.fg = color
+			} else {
+				s.bg = color
+			}
+			i += n
+		}
+	}
+	return s
+}
+
+// parseANSILine splits a line of terminal output into styled runs, starting
+// from style s, and returns the runs with the style in effect at the end.
+// Escape sequences other than SGR are dropped.
+func parseANSILine(line string, s cellStyle) ([]styledRun, cellStyle) {
+	var runs []styledRun
+	col := 0
+	add := func(text string) {
+		text = stripANSI(text)
+		for _, r := range text {
+			w := ansi.StringWidth(string(r))
+			if w == 0 {
+				continue
+			}
+			// Wide characters get a run of their own so every run can be
+			// laid out as single-width cells.
+			n := len(runs)
+			if w == 1 && n > 0 && runs[n-1].style == s && runs[n-1].col+runs[n-1].cells == col && !isWide(runs[n-1].text) {
+				runs[n-1].text += string(r)
+				runs[n-1].cells++
+			} else {
+				runs = append(runs, styledRun{col: col, cells: w, text: string(r), style: s})
+			}
+			col += w
+		}
+	}
+	last := 0
+	for _, loc := range sgrRe.FindAllStringSubmatchIndex(line, -1) {
+		add(line[last:loc[0]])
+		s = applySGR(s, line[loc[2]:loc[3]])
+		last = loc[1]
+	}
+	add(line[last:])
+	return runs, s
+}
+
+// isWide reports whether text is a single double-width character.
+func isWide(text string) bool {
+	return len([]rune(text)) == 1 && ansi.StringWidth(text) == 2
+}
+
+// svgOptions controls an SVG screenshot.
+type svgOptions struct {
+	title  string // window title shown in the chrome
+	chrome bool   // draw a window frame with a title bar
+}
+
+// renderSVG turns terminal output into an SVG image of a terminal showing it,
+// in the given theme's colors.
+func renderSVG(output, style string, opts svgOptions) string {
+	bgIndex, fgIndex, _ := pageColors(style)
+	pageBg, pageFg := xtermHex(bgIndex), xtermHex(fgIndex)
+
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	var rows [][]styledRun
+	cols := 0
+	var s cellStyle
+	for _, line := range lines {
+		var runs []styledRun
+		runs, s = parseANSILine(line, s)
+		rows = append(rows, runs)
+		if n := len(runs); n > 0 {
+			cols = max(cols, runs[n-1].col+runs[n-1].cells)
+		}
+	}
+
+	top := float64(svgPadding)
+	if opts.chrome {
+		top += svgTitleBar
+	}
+	width := float64(cols)*svgCellWidth + 2*svgPadding
+	height := top + float64(len(rows))*svgLineHeight + svgPadding
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%.6g" height="%.6g" viewBox="0 0 %.6g %.6g">`+"\n", width, height, width, height)
+	fmt.Fprintf(&b, `<style>text { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; font-size: %dpx; white-space: pre; }</style>`+"\n", svgFontSize)
+	if opts.chrome {
+		fmt.Fprintf(&b, `<rect width="%.6g" height="%.6g" rx="8" fill="%s"/>`+"\n", width, height, pageBg)
+		border := xtermHex(headerFg(6, style))
+		fmt.Fprintf(&b, `<rect x="0.5" y="0.5" width="%.6g" height="%.6g" rx="8" fill="none" stroke="%s"/>`+"\n", width-1, height-1, border)
+		for i, color := range []string{"#ff5f56", "#ffbd2e", "#27c93f"} {
+			fmt.Fprintf(&b, `<circle cx="%d" cy="%d" r="6" fill="%s"/>`+"\n", 20+i*20, svgTitleBar/2, color)
+		}
+		if opts.title != "" {
+			fmt.Fprintf(&b, `<text x="%.6g" y="%d" fill="%s" text-anchor="middle">%s</text>`+"\n",
+				width/2, svgTitleBar/2+svgFontSize/3, pageFg, html.EscapeString(opts.title))
+		}
+	} else {
+		fmt.Fprintf(&b, `<rect width="%.6g" height="%.6g" fill="%s"/>`+"\n", width, height, pageBg)
+	}
+
+	for i, runs := range rows {
+		y := top + float64(i)*svgLineHeight
+		for _, r := range runs {
+			fg, bg := r.style.fg, r.style.bg
+			if r.style.reverse {
+				fg, bg = bg, fg
+				if fg == "" {
+					fg = pageBg
+				}
+				if bg == "" {
+					bg = pageFg
+				}
+			}
+			if bg != "" {
+				fmt.Fprintf(&b, `<rect x="%.6g" y="%.6g" width="%.6g" height="%d" fill="%s"/>`+"\n",
+					svgPadding+float64(r.col)*svgCellWidth, y, float64(r.cells)*svgCellWidth, svgLineHeight, bg)
+			}
+		}
+		for _, r := range runs {
+			if strings.TrimSpace(r.text) == "" {
+				continue
+			}
+			fg := r.style.fg
+			if r.style.reverse {
+				fg = r.style.bg
+				if fg == "" {
+					fg = pageBg
+				}
+			}
+			if fg == "" {
+				fg = pageFg
+			}
+			attrs := fmt.Sprintf(` fill="%s"`, fg)
+			if r.style.bold {
+				attrs += ` font-weight="bold"`
+			}
+			if r.style.italic {
+				attrs += ` font-style="italic"`
+			}
+			if r.style.faint {
+				attrs += ` fill-opacity="0.6"`
+			}
+			switch {
+			case r.style.underline && r.style.strike:
+				attrs += ` text-decoration="underline line-through"`
+			case r.style.underline:
+				attrs += ` text-decoration="underline"`
+			case r.style.strike:
+				attrs += ` text-decoration="line-through"`
+			}
+			fmt.Fprintf(&b, `<text x="%.6g" y="%.6g" textLength="%.6g" lengthAdjust="spacingAndGlyphs"%s>%s</text>`+"\n",
+				svgPadding+float64(r.col)*svgCellWidth, y+svgLineHeight-5, float64(r.cells)*svgCellWidth, attrs, html.EscapeString(r.text))
+		}
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// exportSVG renders a file's contents the way --no-pager would and returns an
+// SVG screenshot of the result. Colors are rendered even with no terminal
+// attached; anything but "light" gets the dark theme.
+func exportSVG(content, style string, width int, opts renderOptions, svg svgOptions) string {
+	if style != "light" {
+		style = "dark"
+	}
+	return renderSVG(renderANSI(content, style, width, opts), style, svg)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestApplySGR(t *testing.T) {
+	s := applySGR(cellStyle{}, "1;38;5;57;48;2;1;2;3")
+	if !s.bold || s.fg != "#5f00ff" || s.bg != "#010203" {
+		t.Errorf("unexpected style %+v", s)
+	}
+	s = applySGR(s, "22;39")
+	if s.bold || s.fg != "" || s.bg != "#010203" {
+		t.Errorf("expected bold and fg cleared, got %+v", s)
+	}
+	if s = applySGR(s, ""); s != (cellStyle{}) {
+		t.Errorf("expected an empty SGR to reset, got %+v", s)
+	}
+	if s = applySGR(cellStyle{}, "31;104"); s.fg != "#800000" || s.bg != "#0000ff" {
+		t.Errorf("expected basic colors, got %+v", s)
+	}
+}
+
+func TestParseANSILine(t *testing.T) {
+	runs, end := parseANSILine("ab\x1b[1;38;5;15mcd\x1b[0m界e\x1b[3m", cellStyle{})
+	if len(runs) != 4 {
+		t.Fatalf("expected 4 runs, got %+v", runs)
+	}
+	want := []styledRun{
+		{col: 0, cells: 2, text: "ab"},
+		{col: 2, cells: 2, text: "cd", style: cellStyle{fg: "#ffffff", bold: true}},
+		{col: 4, cells: 2, text: "界"},
+		{col: 6, cells: 1, text: "e"},
+	}
+	for i := range want {
+		if runs[i] != want[i] {
+			t.Errorf("run %d: got %+v, want %+v", i, runs[i], want[i])
+		}
+	}
+	if !end.italic {
+		t.Error("expected the trailing style to carry over")
+	}
+}
+
+// checkSVG fails unless out is well-formed XML.
+func checkSVG(t *testing.T, out string) {
+	t.Helper()
+	d := xml.NewDecoder(strings.NewReader(out))
+	for {
+		if _, err := d.Token(); err == io.EOF {
+			return
+		} else if err != nil {
+			t.Fatalf("invalid SVG: %v\n%s", err, out)
+		}
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	out := renderSVG("\x1b[48;5;57m\x1b[38;5;15m Title \x1b[0m\nplain <&> text\n", "dark", svgOptions{})
+	checkSVG(t, out)
+	for _, want := range []string{
+		`fill="#5f00ff"`,
+		`> Title </text>`,
+		`fill="#ffffff"`,
+		`>plain &lt;&amp;&gt; text</text>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in SVG", want)
+		}
+	}
+	if strings.Contains(out, "<circle") {
+		t.Error("expected no window chrome by default")
+	}
+}
+
+func TestRenderSVG_Chrome(t *testing.T) {
+	out := renderSVG("hello\n", "light", svgOptions{title: "doc.md", chrome: true})
+	checkSVG(t, out)
+	if strings.Count(out, "<circle") != 3 || !strings.Contains(out, ">doc.md</text>") {
+		t.Errorf("expected window chrome with a title, got %s", out)
+	}
+}
+
+func TestExportSVG_ColorsWithoutTerminal(t *testing.T) {
+	out := exportSVG("# Title\n\nText.\n", "dark", 40, renderOptions{}, svgOptions{})
+	checkSVG(t, out)
+	_, bg, _ := headerColors(1, "dark")
+	if !strings.Contains(out, `fill="`+xtermHex(bg)+`"`) {
+		t.Errorf("expected the heading pill background in the SVG, got %s", out)
+	}
+	if !strings.Contains(out, ">Title</text>") || !strings.Contains(out, ">Text.") {
+		t.Errorf("expected the document text in the SVG, got %s", out)
+	}
+}
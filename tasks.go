@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// taskRe matches a GFM task list item in markdown source. Group 1 = indent,
+// group 2 = state (" ", "x" or "X"), group 3 = item text.
+var taskRe = regexp.MustCompile(`^(\s*)(?:[-*+]|\d+[.)])\s+\[([ xX])\]\s+(.*)$`)
+
+// renderedTaskRe matches the checkbox glamour renders at the start of a task
+// item line, once ANSI codes are stripped. Group 1 = checkbox.
+var renderedTaskRe = regexp.MustCompile(`^\s*(\[[ x✓]\] )`)
+
+// taskGlyphRe matches a task list item line after styleTaskItems, once ANSI
+// codes are stripped.
+var taskGlyphRe = regexp.MustCompile(`^\s*[☐☑] `)
+
+// errFileChanged is returned when a task toggle finds the file on disk no
+// longer matches what the pager rendered.
+var errFileChanged = errors.New("file changed on disk, reopen to edit tasks")
+
+// fenceRe matches the opening or closing line of a fenced code block.
+var fenceRe = regexp.MustCompile("^\\s*(```|~~~)")
+
+type taskItem struct {
+	line int // 1-based line number in the source
+	done bool
+	text string
+	path []string // enclosing heading texts, outermost first
+}
+
+// scanTasks returns every task list item in md outside fenced code blocks,
+// together with the path of headings it sits under.
+func scanTasks(md string) []taskItem {
+	var tasks []taskItem
+	var stack []headerBlock
+	fence := ""
+	for i, line := range strings.Split(md, "\n") {
+		if sub := fenceRe.FindStringSubmatch(line); sub != nil {
+			if fence == "" {
+				fence = sub[1]
+			} else if sub[1] == fence {
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+		if sub := headerRe.FindStringSubmatch(line); sub != nil {
+			h := headerBlock{level: len(sub[1]), text: stripInlineMarkdown(sub[2])}
+			for len(stack) > 0 && stack[len(stack)-1].level >= h.level {
+				stack = stack[:len(stack)-1]
+			}
+			stack = append(stack, h)
+			continue
+		}
+		if sub := taskRe.FindStringSubmatch(line); sub != nil {
+			path := make([]string, len(stack))
+			for j, h := range stack {
+				path[j] = h.text
+			}
+			tasks = append(tasks, taskItem{
+				line: i + 1,
+				done: sub[2] != " ",
+				text: strings.TrimSpace(sub[3]),
+				path: path,
+			})
+		}
+	}
+	return tasks
+}
+
+// countSectionTasks fills in the task counters of headers from prose, the
+// output of extractHeaders. A heading counts every task up to the next heading
+// of the same or a higher level, subsections included.
+func countSectionTasks(prose string, headers []headerBlock) {
+	var open []int // indices of the headings enclosing the current line
+	for _, line := range strings.Split(prose, "\n") {
+		if sub := headerPlaceholderRe.FindStringSubmatch(line); sub != nil {
+			j, _ := strconv.Atoi(sub[1])
+			if j >= len(headers) {
+				continue
+			}
+			for len(open) > 0 && headers[open[len(open)-1]].level >= headers[j].level {
+				open = open[:len(open)-1]
+			}
+			open = append(open, j)
+			continue
+		}
+		sub := taskRe.FindStringSubmatch(line)
+		if sub == nil {
+			continue
+		}
+		for _, j := range open {
+			headers[j].total++
+			if sub[2] != " " {
+				headers[j].done++
+			}
+		}
+	}
+}
+
+// taskColors returns the 256-color indices for open and completed task glyphs.
+func taskColors(style string) (open, done string) {
+	if style == "light" {
+		return "130", "28"
+	}
+	return "214", "35"
+}
+
+// renderTaskGlyph returns the checkbox glyph for a task, in theme colors.
+func renderTaskGlyph(done bool, style string) string {
+	open, doneColor := taskColors(style)
+	glyph, color := "☐", open
+	if done {
+		glyph, color = "☑", doneColor
+	}
+	if style == "notty" {
+		return glyph
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Render(glyph)
+}
+
+// renderTaskCounter renders the done/total counter shown next to a heading.
+func renderTaskCounter(h headerBlock, style string) string {
+	counter := fmt.Sprintf("%d/%d", h.done, h.total)
+	if style == "notty" {
+		return counter
+	}
+	open, done := taskColors(style)
+	color := open
+	if h.done == h.total {
+		color = done
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Bold(true).Render(counter)
+}
+
+// styleTaskItems replaces the checkboxes glamour draws for task list items in
+// rendered with ☐/☑ glyphs.
+func styleTaskItems(rendered, style string) string {
+	lines := strings.Split(rendered, "\n")
+	for i, line := range lines {
+		sub := renderedTaskRe.FindStringSubmatch(stripANSI(line))
+		if sub == nil {
+			continue
+		}
+		glyph := renderTaskGlyph(sub[1] != "[ ] ", style) + " "
+		lines[i] = strings.Replace(line, sub[1], glyph, 1)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatOpenTasks lists the open tasks in md grouped under their section
+// path, for the --tasks mode.
+func formatOpenTasks(md string) string {
+	var b strings.Builder
+	last, started := "", false
+	for _, t := range scanTasks(md) {
+		if t.done {
+			continue
+		}
+		path := strings.Join(t.path, " › ")
+		if !started || path != last {
+			if started {
+				b.WriteString("\n")
+			}
+			if path != "" {
+				b.WriteString(path + "\n")
+			}
+			last, started = path, true
+		}
+		fmt.Fprintf(&b, "  ☐ %s\n", t.text)
+	}
+	return b.String()
+}
+
+// renderedTaskLines returns the indices of the task list item lines in the
+// ANSI-stripped rendered lines, in document order.
+func renderedTaskLines(lines []string) []int {
+	var idx []int
+	for i, l := range lines {
+		if taskGlyphRe.MatchString(l) {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// markTaskCursor prefixes line i of rendered with the task cursor, in place of
+// the left margin.
+func markTaskCursor(rendered string, i int, style string) string {
+	lines := strings.Split(rendered, "\n")
+	if i < 0 || i >= len(lines) {
+		return rendered
+	}
+	cursor := "▸ "
+	if style != "notty" {
+		cursor = lipgloss.NewStyle().Foreground(lipgloss.Color("99")).Bold(true).Render(cursor)
+	}
+	lines[i] = cursor + ansi.TruncateLeft(lines[i], 2, "")
+	return strings.Join(lines, "\n")
+}
+
+// toggleTask flips the checkbox of the task item on the given 1-based line of
+// source and returns the new contents.
+func toggleTask(source string, line int) (string, error) {
+	lines := strings.Split(source, "\n")
+	if line < 1 || line > len(lines) {
+		return "", fmt.Errorf("line %d out of range", line)
+	}
+	l := lines[line-1]
+	loc := taskRe.FindStringSubmatchIndex(l)
+	if loc == nil {
+		return "", fmt.Errorf("line %d is not a task list item", line)
+	}
+	state := "x"
+	if l[loc[4]:loc[5]] != " " {
+		state = " "
+	}
+	lines[line-1] = l[:loc[4]] + state + l[loc[5]:]
+	return strings.Join(lines, "\n"), nil
+}
+
+// toggleTaskInFile toggles the task on the given 1-based line of filename. The
+// file must still hold expected, the contents the caller rendered; the update
+// is written atomically and the new contents returned.
+func toggleTaskInFile(filename, expected string, line int) (string, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	if string(data) != expected {
+		return "", errFileChanged
+	}
+	updated, err := toggleTask(expected, line)
+	if err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(filename, []byte(updated), info.Mode().Perm()); err != nil {
+		return "", err
+	}
+	return updated, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to filename and renames
+// it into place, so readers never see a partial write.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
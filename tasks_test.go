@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// scanTasks tests
+
+func TestScanTasks_PathsAndLines(t *testing.T) {
+	md := "# Release\n\n- [x] Build\n\n## Docs\n\n- [ ] Changelog\n\n# Other\n\n1. [ ] Ship\n"
+	tasks := scanTasks(md)
+	if len(tasks) != 3 {
+		t.Fatalf("expected 3 tasks, got %d", len(tasks))
+	}
+	if !tasks[0].done || tasks[0].line != 3 {
+		t.Errorf("unexpected first task: %+v", tasks[0])
+	}
+	if got := strings.Join(tasks[1].path, "/"); got != "Release/Docs" || tasks[1].text != "Changelog" {
+		t.Errorf("unexpected second task path %q text %q", got, tasks[1].text)
+	}
+	if got := strings.Join(tasks[2].path, "/"); got != "Other" {
+		t.Errorf("expected sibling heading to reset path, got %q", got)
+	}
+}
+
+func TestScanTasks_SkipsFencedCode(t *testing.T) {
+	md := "```\n- [ ] not a task\n```\n~~~\n- [ ] nor this\n~~~\n- [ ] real\n"
+	tasks := scanTasks(md)
+	if len(tasks) != 1 || tasks[0].text != "real" {
+		t.Errorf("expected only the real task, got %+v", tasks)
+	}
+}
+
+// countSectionTasks tests
+
+func TestCountSectionTasks_IncludesSubsections(t *testing.T) {
+	prose, headers := extractHeaders("# A\n- [x] one\n## B\n- [ ] two\n- [x] three\n# C\ntext\n")
+	countSectionTasks(prose, headers)
+	want := [][2]int{{2, 3}, {1, 2}, {0, 0}}
+	for i, w := range want {
+		if headers[i].done != w[0] || headers[i].total != w[1] {
+			t.Errorf("header %d: got %d/%d, want %d/%d", i, headers[i].done, headers[i].total, w[0], w[1])
+		}
+	}
+}
+
+// rendering tests
+
+func TestRenderHeader_TaskCounter(t *testing.T) {
+	out := renderHeader(headerBlock{level: 2, text: "Checklist", done: 3, total: 7}, "notty")
+	if out != "Checklist 3/7" {
+		t.Errorf("expected counter after heading, got %q", out)
+	}
+	if out := renderHeader(headerBlock{level: 2, text: "Plain"}, "notty"); out != "Plain" {
+		t.Errorf("expected no counter without tasks, got %q", out)
+	}
+}
+
+func TestRenderMarkdown_TaskGlyphs(t *testing.T) {
+	out := stripANSI(renderMarkdown("## Todo\n\n- [ ] open\n- [x] done\n\nNot [ ] a task", "dark", 60))
+	for _, want := range []string{"☐ open", "☑ done", "Todo", "1/2", "Not [ ] a task"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got %q", want, out)
+		}
+	}
+	if strings.Contains(out, "[✓]") {
+		t.Error("expected glamour checkboxes replaced")
+	}
+}
+
+// formatOpenTasks tests
+
+func TestFormatOpenTasks(t *testing.T) {
+	md := "- [ ] loose\n\n# Release\n\n## Docs\n\n- [ ] Changelog\n- [x] Done\n"
+	want := "  ☐ loose\n\nRelease › Docs\n  ☐ Changelog\n"
+	if got := formatOpenTasks(md); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := formatOpenTasks("- [x] all done\n"); got != "" {
+		t.Errorf("expected no output when nothing is open, got %q", got)
+	}
+}
+
+// toggle tests
+
+func TestToggleTask_FlipsState(t *testing.T) {
+	src := "# T\n- [ ] a\n  * [X] b\r\n"
+	got, err := toggleTask(src, 2)
+	if err != nil || got != "# T\n- [x] a\n  * [X] b\r\n" {
+		t.Fatalf("unexpected toggle result %q, %v", got, err)
+	}
+	got, err = toggleTask(got, 3)
+	if err != nil || !strings.Contains(got, "  * [ ] b\r") {
+		t.Fatalf("unexpected toggle result %q, %v", got, err)
+	}
+	if _, err := toggleTask(src, 1); err == nil {
+		t.Error("expected error for a line that is not a task")
+	}
+}
+
+func TestToggleTaskInFile_WritesAndChecksChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.md")
+	src := "- [ ] one\n- [ ] two\n"
+	if err := os.WriteFile(path, []byte(src), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := toggleTaskInFile(path, src, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "- [ ] one\n- [x] two\n" || string(data) != updated {
+		t.Errorf("unexpected file contents %q", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o640 {
+		t.Errorf("expected permissions preserved, got %v", info.Mode().Perm())
+	}
+	if _, err := toggleTaskInFile(path, src, 1); err != errFileChanged {
+		t.Errorf("expected errFileChanged for stale contents, got %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files left behind, got %d entries", len(entries))
+	}
+}
+
+// model tests
+
+func TestModel_TaskModeTogglesSourceLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.md")
+	src := "---\ntitle: Plan\n---\n# Plan\n\n- [ ] one\n- [ ] two\n"
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var tm tea.Model = newModel(path, src, "notty")
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 60, Height: 20})
+	for _, key := range []string{"t", "j", " "} {
+		tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	}
+	m := tm.(model)
+	if m.taskErr != "" {
+		t.Fatalf("unexpected error: %s", m.taskErr)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "- [ ] one\n- [x] two") || !strings.HasPrefix(string(data), "---\ntitle: Plan") {
+		t.Errorf("expected second task ticked in place, got %q", data)
+	}
+	if !strings.Contains(stripANSI(m.rendered), "☑ two") || !strings.Contains(m.viewport.View(), "▸") {
+		t.Error("expected re-rendered view with the task cursor")
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Markers delimiting the table of contents that --update-toc rewrites.
+const (
+	tocStart = "<!-- toc -->"
+	tocStop  = "<!-- tocstop -->"
+)
+
+// errNoTOCMarkers is returned by updateTOC when the document has no region to
+// rewrite.
+var errNoTOCMarkers = errors.New("no " + tocStart + " ... " + tocStop + " region found")
+
+// tocOptions selects what an outline shows.
+type tocOptions struct {
+	format string // "text", "markdown" or "json"
+	lines  bool   // show 1-based source line numbers
+	slugs  bool   // show anchor slugs
+
+	section string // outline only this section, named as for --section
+}
+
+// tocEntry is a heading in the JSON outline.
+type tocEntry struct {
+	Level    int         `json:"level"`
+	Text     string      `json:"text"`
+	Slug     string      `json:"slug"`
+	Line     int         `json:"line"`
+	Children []*tocEntry `json:"children,omitempty"`
+}
+
+// documentHeadings returns the headings of a file's contents, skipping front
+// matter, with lines counted from the start of the file.
+func documentHeadings(content string) []sourceHeading {
+	body, _ := extractFrontMatter(content)
+	offset := strings.Count(content[:len(content)-len(body)], "\n")
+	headings := scanHeadings(body)
+	for i := range headings {
+		headings[i].line += offset
+	}
+	return headings
+}
+
+// minLevel returns the shallowest heading level, so outlines of documents
+// without an h1 are not indented.
+func minLevel(headings []sourceHeading) int {
+	level := 6
+	for _, h := range headings {
+		level = min(level, h.level)
+	}
+	return level
+}
+
+// formatTOC renders the outline of a file's contents.
+func formatTOC(content string, opts tocOptions) (string, error) {
+	headings := documentHeadings(content)
+	if opts.section != "" {
+		// Outline the section in place, so its line numbers and anchors are
+		// those of the whole document.
+		body, _ := extractFrontMatter(content)
+		offset := strings.Count(content[:len(content)-len(body)], "\n")
+		start, end, ok := sectionRange(body, opts.section)
+		if !ok {
+			return "", errors.New(sectionNotFound(body, opts.section))
+		}
+		var in []sourceHeading
+		for _, h := range headings {
+			if h.line >= start+offset && h.line < end+offset {
+				in = append(in, h)
+			}
+		}
+		headings = in
+	}
+	switch opts.format {
+	case "", "text":
+		return formatTOCText(headings, opts), nil
+	case "markdown", "md":
+		return formatTOCMarkdown(headings), nil
+	case "json":
+		return formatTOCJSON(headings)
+	}
+	return "", fmt.Errorf("unknown toc format %q (known: text, markdown, json)", opts.format)
+}
+
+// formatTOCText renders headings as an indented tree.
+func formatTOCText(headings []sourceHeading, opts tocOptions) string {
+	base := minLevel(headings)
+	width := 1
+	if len(headings) > 0 {
+		width = len(fmt.Sprint(headings[len(headings)-1].line + 1))
+	}
+	var b strings.Builder
+	for _, h := range headings {
+		if opts.lines {
+			fmt.Fprintf(&b, "%*d  ", width, h.line+1)
+		}
+		b.WriteString(strings.Repeat("  ", h.level-base) + h.text)
+		if opts.slugs {
+			b.WriteString("  #" + h.slug)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// formatTOCMarkdown renders headings as a nested list of anchor links.
+func formatTOCMarkdown(headings []sourceHeading) string {
+	base := minLevel(headings)
+	var b strings.Builder
+	for _, h := range headings {
+		fmt.Fprintf(&b, "%s- [%s](#%s)\n", strings.Repeat("  ", h.level-base), h.text, h.slug)
+	}
+	return b.String()
+}
+
+// formatTOCJSON renders headings as a JSON tree.
+func formatTOCJSON(headings []sourceHeading) (string, error) {
+	roots := []*tocEntry{}
+	var stack []*tocEntry
+	for _, h := range headings {
+		e := &tocEntry{Level: h.level, Text: h.text, Slug: h.slug, Line: h.line + 1}
+		for len(stack) > 0 && stack[len(stack)-1].Level >= h.level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, e)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, e)
+		}
+		stack = append(stack, e)
+	}
+	data, err := json.MarshalIndent(roots, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// updateTOC replaces the region between the toc markers in content with a
+// markdown table of contents of the document. Markers inside fenced code
+// blocks are examples, not the region.
+func updateTOC(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	start, stop := -1, -1
+	fence := ""
+	for i, line := range lines {
+		if sub := fenceRe.FindStringSubmatch(line); sub != nil {
+			if fence == "" {
+				fence = sub[1]
+			} else if sub[1] == fence {
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+		switch strings.TrimSpace(line) {
+		case tocStart:
+			if start < 0 {
+				start = i
+			}
+		case tocStop:
+			if start >= 0 && stop < 0 {
+				stop = i
+			}
+		}
+	}
+	if start < 0 || stop < 0 {
+		return "", errNoTOCMarkers
+	}
+	toc := strings.Split(strings.TrimRight(formatTOCMarkdown(documentHeadings(content)), "\n"), "\n")
+	region := append([]string{lines[start], ""}, toc...)
+	region = append(region, "", lines[stop])
+	out := append(append(append([]string{}, lines[:start]...), region...), lines[stop+1:]...)
+	return strings.Join(out, "\n"), nil
+}
+
+// updateTOCFile rewrites the table of contents in filename. The file is left
+// untouched when the table is already up to date.
+func updateTOCFile(filename string) error {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	updated, err := updateTOC(string(data))
+	if err != nil || updated == string(data) {
+		return err
+	}
+	return writeFileAtomic(filename, []byte(updated), info.Mode().Perm())
+}
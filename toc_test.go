@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const tocDoc = "---\ntitle: Tool\n---\n# Tool\n\n## Install\n\n### From source\n\n```\n# comment\n```\n\n## Usage\n\n## Usage\n"
+
+func TestFormatTOC_Text(t *testing.T) {
+	got, err := formatTOC(tocDoc, tocOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Tool\n  Install\n    From source\n  Usage\n  Usage\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatTOC_TextLinesAndSlugs(t *testing.T) {
+	got, _ := formatTOC(tocDoc, tocOptions{lines: true, slugs: true})
+	for _, want := range []string{" 4  Tool  #tool\n", " 8      From source  #from-source\n", "16    Usage  #usage-1\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+}
+
+func TestFormatTOC_Section(t *testing.T) {
+	got, err := formatTOC(tocDoc, tocOptions{lines: true, slugs: true, section: "Install"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "6  Install  #install\n8    From source  #from-source\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	got, _ = formatTOC(tocDoc, tocOptions{lines: true, slugs: true, section: "usage-1"})
+	if want := "16  Usage  #usage-1\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, err := formatTOC(tocDoc, tocOptions{section: "Nope"}); err == nil {
+		t.Error("expected an error for an unknown section")
+	}
+}
+
+func TestFormatTOC_Markdown(t *testing.T) {
+	got, _ := formatTOC("## A\n\n### B\n\n## A\n", tocOptions{format: "markdown"})
+	want := "- [A](#a)\n  - [B](#b)\n- [A](#a-1)\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatTOC_JSON(t *testing.T) {
+	got, err := formatTOC(tocDoc, tocOptions{format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var roots []tocEntry
+	if err := json.Unmarshal([]byte(got), &roots); err != nil {
+		t.Fatalf("invalid JSON %q: %v", got, err)
+	}
+	if len(roots) != 1 || len(roots[0].Children) != 3 {
+		t.Fatalf("unexpected tree %+v", roots)
+	}
+	install := roots[0].Children[0]
+	if install.Text != "Install" || install.Line != 6 || install.Children[0].Slug != "from-source" {
+		t.Errorf("unexpected Install entry %+v", install)
+	}
+}
+
+func TestFormatTOC_UnknownFormat(t *testing.T) {
+	if _, err := formatTOC(tocDoc, tocOptions{format: "yaml"}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestUpdateTOC(t *testing.T) {
+	doc := "# Tool\n\n<!-- toc -->\n- [Old](#old)\n<!-- tocstop -->\n\n## Usage\n"
+	got, err := updateTOC(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Tool\n\n<!-- toc -->\n\n- [Tool](#tool)\n  - [Usage](#usage)\n\n<!-- tocstop -->\n\n## Usage\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	again, _ := updateTOC(got)
+	if again != got {
+		t.Errorf("expected a second update to be a no-op, got %q", again)
+	}
+	if _, err := updateTOC("# Tool\n"); !errors.Is(err, errNoTOCMarkers) {
+		t.Errorf("expected errNoTOCMarkers, got %v", err)
+	}
+}
+
+func TestUpdateTOC_IgnoresMarkersInFences(t *testing.T) {
+	example := "```md\n<!-- toc -->\n<!-- tocstop -->\n```\n"
+	doc := "# Tool\n\n" + example + "\n<!-- toc -->\n<!-- tocstop -->\n\n## Usage\n"
+	got, err := updateTOC(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Tool\n\n" + example + "\n<!-- toc -->\n\n- [Tool](#tool)\n  - [Usage](#usage)\n\n<!-- tocstop -->\n\n## Usage\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, err := updateTOC("# Tool\n\n" + example); !errors.Is(err, errNoTOCMarkers) {
+		t.Errorf("expected errNoTOCMarkers for markers only in a fence, got %v", err)
+	}
+}
+
+func TestUpdateTOCFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.md")
+	if err := os.WriteFile(path, []byte("<!-- toc -->\n<!-- tocstop -->\n\n# Title\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateTOCFile(path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "- [Title](#title)") {
+		t.Errorf("expected the TOC written to the file, got %q", data)
+	}
+}
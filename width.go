@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// defaultWidth is the render width when nothing better is known, e.g. when
+// output goes to a pipe.
+const defaultWidth = 80
+
+// defaultReadingWidth is the reading width used when the pager's reading mode
+// is switched on without a --max-width.
+const defaultReadingWidth = 100
+
+// resolveWidth picks the render width: an explicit --width, then the COLUMNS
+// environment variable, then the size of the terminal on fd when it is one,
+// else defaultWidth.
+func resolveWidth(requested int, fd int) int {
+	if requested > 0 {
+		return requested
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("COLUMNS"))); err == nil && n > 0 {
+		return n
+	}
+	if term.IsTerminal(fd) {
+		if w, _, err := term.GetSize(fd); err == nil && w > 0 {
+			return w
+		}
+	}
+	return defaultWidth
+}
+
+// pagerWidth returns the render width for a pager window of the given width:
+// an explicit --width, narrowed to fit the window, or the window itself.
+func pagerWidth(requested, window int) int {
+	if requested > 0 {
+		return min(requested, window)
+	}
+	return window
+}
+
+// centerMargin returns the left margin that centers a column of width cells
+// in a window.
+func centerMargin(width, window int) int {
+	return max((window-width)/2, 0)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// notTerminal returns the descriptor of a regular file.
+func notTerminal(t *testing.T) int {
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return int(f.Fd())
+}
+
+func TestResolveWidth(t *testing.T) {
+	fd := notTerminal(t)
+
+	t.Setenv("COLUMNS", "")
+	if got := resolveWidth(0, fd); got != defaultWidth {
+		t.Errorf("expected the default width for a pipe, got %d", got)
+	}
+	t.Setenv("COLUMNS", "132")
+	if got := resolveWidth(0, fd); got != 132 {
+		t.Errorf("expected COLUMNS to be used, got %d", got)
+	}
+	if got := resolveWidth(60, fd); got != 60 {
+		t.Errorf("expected --width to win over COLUMNS, got %d", got)
+	}
+	t.Setenv("COLUMNS", "wide")
+	if got := resolveWidth(0, fd); got != defaultWidth {
+		t.Errorf("expected an invalid COLUMNS to be ignored, got %d", got)
+	}
+}
+
+func TestPagerWidth(t *testing.T) {
+	cases := []struct{ requested, window, want int }{
+		{0, 120, 120},
+		{80, 120, 80},
+		{200, 120, 120},
+	}
+	for _, c := range cases {
+		if got := pagerWidth(c.requested, c.window); got != c.want {
+			t.Errorf("pagerWidth(%d, %d) = %d, want %d", c.requested, c.window, got, c.want)
+		}
+	}
+}
+
+// model tests
+
+func TestModel_RequestedWidth(t *testing.T) {
+	m := newModel("doc.md", "# Title\n\nText.\n", "notty")
+	m.width = 40
+	tm, _ := m.Update(tea.WindowSizeMsg{Width: 100, Height: 20})
+	if got := tm.(model).lastWidth; got != 40 {
+		t.Errorf("expected content rendered at the requested width, got %d", got)
+	}
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 30, Height: 20})
+	if got := tm.(model).lastWidth; got != 30 {
+		t.Errorf("expected a narrower window to win, got %d", got)
+	}
+}
+
+func TestModel_ReadingMode(t *testing.T) {
+	m := newModel("doc.md", "# Title\n\n"+strings.Repeat("word ", 80)+"\n", "notty")
+	m.maxWidth = 60
+	m.reading = true
+	tm, _ := m.Update(tea.WindowSizeMsg{Width: 160, Height: 20})
+	if got := tm.(model).lastWidth; got != 60 {
+		t.Fatalf("expected content rendered at the reading width, got %d", got)
+	}
+	body := strings.Split(tm.View(), "\n")[1:]
+	for _, line := range body[:5] {
+		if strings.TrimSpace(line) != "" && !strings.HasPrefix(line, strings.Repeat(" ", 50)) {
+			t.Errorf("expected the column centered, got %q", line)
+		}
+	}
+
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w")})
+	if got := tm.(model).lastWidth; got != 160 {
+		t.Errorf("expected w to switch to the full width, got %d", got)
+	}
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w")})
+	if got := tm.(model).lastWidth; got != 60 {
+		t.Errorf("expected w to switch back to the reading width, got %d", got)
+	}
+}
+
+func TestModel_ReadingModeDefaultWidth(t *testing.T) {
+	m := newModel("doc.md", "Text.\n", "notty")
+	tm, _ := m.Update(tea.WindowSizeMsg{Width: 200, Height: 20})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w")})
+	if got := tm.(model).lastWidth; got != defaultReadingWidth {
+		t.Errorf("expected the default reading width, got %d", got)
+	}
+}